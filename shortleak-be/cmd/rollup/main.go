@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"shortleak/config"
+	"shortleak/database"
+	"shortleak/repositories"
+	"shortleak/services"
+	"time"
+)
+
+var (
+	rollupVisits      = services.RollupVisits
+	earliestVisitTime = repositories.EarliestVisitTime
+	now               = time.Now
+)
+
+/** RunRollup backfills daily visit rollups, by default from the first visit up to yesterday */
+func RunRollup(args []string) {
+	flags := flag.NewFlagSet("rollup", flag.ContinueOnError)
+	flags.SetOutput(os.Stdout)
+	fromFlag := flags.String("from", "", "first day to roll up (YYYY-MM-DD), defaults to the first visit")
+	toFlag := flags.String("to", "", "day to stop before (YYYY-MM-DD), defaults to today")
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+
+	to := now().UTC()
+	if *toFlag != "" {
+		parsed, err := time.Parse("2006-01-02", *toFlag)
+		if err != nil {
+			fmt.Println("❌ Invalid -to date:", *toFlag)
+			return
+		}
+		to = parsed
+	}
+	var from time.Time
+	if *fromFlag != "" {
+		parsed, err := time.Parse("2006-01-02", *fromFlag)
+		if err != nil {
+			fmt.Println("❌ Invalid -from date:", *fromFlag)
+			return
+		}
+		from = parsed
+	}
+
+	cfg := config.LoadConfig()
+	database.ConnectDBFunc(cfg)
+
+	if from.IsZero() {
+		earliest, ok, err := earliestVisitTime()
+		if err != nil {
+			fmt.Println("❌ Rollup failed:", err)
+			return
+		}
+		if !ok {
+			fmt.Println("✅ No visits to roll up")
+			return
+		}
+		from = earliest
+	}
+
+	if err := rollupVisits(from, to); err != nil {
+		fmt.Println("❌ Rollup failed:", err)
+		return
+	}
+	fmt.Printf("✅ Rollup success! %s up to %s\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
+}
+
+func main() {
+	RunRollup(os.Args[1:])
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"shortleak/config"
+	"shortleak/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = os.Setenv("NODE_ENV", "test")
+	_ = os.Setenv("DB_DATABASE_TEST", "shortleak-test")
+	_ = os.Setenv("DB_USERNAME_TEST", "postgres")
+	_ = os.Setenv("DB_PASSWORD_TEST", "12345")
+	_ = os.Setenv("DB_HOST_TEST", "localhost")
+	_ = os.Setenv("DB_DIALECT_TEST", "postgres")
+	_ = os.Setenv("DB_PORT_TEST", "5432")
+}
+
+// helper untuk capture output stdout
+func captureOutput(f func()) string {
+	var buf bytes.Buffer
+	stdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	f()
+
+	w.Close()
+	os.Stdout = stdout
+	buf.ReadFrom(r)
+	return buf.String()
+}
+
+// mock koneksi DB dan rollup, return range yang dipakai
+func mockRollup(t *testing.T, err error) *[]time.Time {
+	called := []time.Time{}
+	origConnect := database.ConnectDBFunc
+	database.ConnectDBFunc = func(cfg config.Config) {}
+	origRollup := rollupVisits
+	rollupVisits = func(from, to time.Time) error {
+		called = append(called, from, to)
+		return err
+	}
+	origNow := now
+	now = func() time.Time { return time.Date(2025, 10, 17, 9, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() {
+		database.ConnectDBFunc = origConnect
+		rollupVisits = origRollup
+		now = origNow
+	})
+	return &called
+}
+
+func TestRunRollupWithRange(t *testing.T) {
+	called := mockRollup(t, nil)
+
+	output := captureOutput(func() {
+		RunRollup([]string{"-from", "2025-10-01", "-to", "2025-10-05"})
+	})
+
+	assert.Equal(t, "✅ Rollup success! 2025-10-01 up to 2025-10-05\n", output)
+	assert.Equal(t, []time.Time{
+		time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 10, 5, 0, 0, 0, 0, time.UTC),
+	}, *called)
+}
+
+func TestRunRollupDefaultsToFirstVisit(t *testing.T) {
+	called := mockRollup(t, nil)
+	origEarliest := earliestVisitTime
+	earliestVisitTime = func() (time.Time, bool, error) {
+		return time.Date(2025, 9, 1, 13, 0, 0, 0, time.UTC), true, nil
+	}
+	defer func() { earliestVisitTime = origEarliest }()
+
+	output := captureOutput(func() {
+		RunRollup([]string{})
+	})
+
+	assert.Equal(t, "✅ Rollup success! 2025-09-01 up to 2025-10-17\n", output)
+	assert.Len(t, *called, 2)
+}
+
+func TestRunRollupNoVisits(t *testing.T) {
+	called := mockRollup(t, nil)
+	origEarliest := earliestVisitTime
+	earliestVisitTime = func() (time.Time, bool, error) { return time.Time{}, false, nil }
+	defer func() { earliestVisitTime = origEarliest }()
+
+	output := captureOutput(func() {
+		RunRollup([]string{})
+	})
+
+	assert.Equal(t, "✅ No visits to roll up\n", output)
+	assert.Empty(t, *called)
+}
+
+func TestRunRollupInvalidDate(t *testing.T) {
+	called := mockRollup(t, nil)
+
+	output := captureOutput(func() {
+		RunRollup([]string{"-from", "kemarin"})
+	})
+
+	assert.Equal(t, "❌ Invalid -from date: kemarin\n", output)
+	assert.Empty(t, *called)
+}
+
+func TestRunRollupFailed(t *testing.T) {
+	mockRollup(t, errors.New("connection lost"))
+
+	output := captureOutput(func() {
+		RunRollup([]string{"-from", "2025-10-01"})
+	})
+
+	assert.Equal(t, "❌ Rollup failed: connection lost\n", output)
+}
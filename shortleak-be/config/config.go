@@ -3,7 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +18,52 @@ type Config struct {
 	Host     string
 	Dialect  string
 	Port     string
+	/** ExpiredLinkURL is where expired links redirect to, empty means respond 410 Gone */
+	ExpiredLinkURL string
+	/** GeoIPDatabasePath is a local MaxMind MMDB file used to geolocate visits, empty disables it */
+	GeoIPDatabasePath string
+	/** TrustedCountryHeader is a header set by a trusted proxy with the visitor country, e.g. CF-IPCountry */
+	TrustedCountryHeader string
+	/** TrustedProxies are the proxy addresses or CIDRs whose X-Forwarded-For is believed, empty trusts none */
+	TrustedProxies []string
+	/** RollupInterval is how often visits are aggregated into daily rollups, zero disables the worker */
+	RollupInterval time.Duration
+	/** VisitQueueSize bounds the visits waiting to be written, visits beyond it are dropped */
+	VisitQueueSize int
+	/** VisitBatchSize is the number of visits written per insert */
+	VisitBatchSize int
+	/** VisitFlushInterval is the longest a queued visit waits before it is written */
+	VisitFlushInterval time.Duration
+	/** OpenGraphUserAgent is sent when fetching destinations for link previews */
+	OpenGraphUserAgent string
+	/** OpenGraphTimeout bounds a whole OpenGraph fetch, including redirects */
+	OpenGraphTimeout time.Duration
+	/** OpenGraphMaxBodyBytes is how much of a destination page is read for OpenGraph tags */
+	OpenGraphMaxBodyBytes int
+	/** DefaultRedirectType is the status code of links without their own redirect type */
+	DefaultRedirectType int
+	/** AccessTokenTTL is how long a JWT issued at login or refresh is valid */
+	AccessTokenTTL time.Duration
+	/** RefreshTokenTTL is how long a session lasts without being refreshed */
+	RefreshTokenTTL time.Duration
+	/** AppURL is the frontend address used for links sent by mail */
+	AppURL string
+	/** PasswordResetTTL is how long a password reset link can be used */
+	PasswordResetTTL time.Duration
+	/** EmailVerificationRequired blocks link creation until the user verified their email */
+	EmailVerificationRequired bool
+	/** EmailVerificationTTL is how long an email verification link can be used */
+	EmailVerificationTTL time.Duration
+	/** MailDriver is smtp to send mail, or log to write it to MailFilePath or the log */
+	MailDriver   string
+	MailFrom     string
+	MailFilePath string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	/** MailTimeout bounds one SMTP session */
+	MailTimeout time.Duration
 }
 
 var LogFatalf = log.Fatalf
@@ -41,6 +89,39 @@ func LoadConfig() Config {
 		Host:     getEnv("DB_HOST"+suffix, ""),
 		Dialect:  getEnv("DB_DIALECT"+suffix, "postgres"),
 		Port:     getEnv("DB_PORT"+suffix, "5432"),
+
+		ExpiredLinkURL:       getEnv("EXPIRED_LINK_URL", ""),
+		DefaultRedirectType:  getIntEnv("DEFAULT_REDIRECT_TYPE", 302),
+		GeoIPDatabasePath:    getEnv("GEOIP_DB_PATH", ""),
+		TrustedCountryHeader: getEnv("TRUSTED_COUNTRY_HEADER", ""),
+		TrustedProxies:       getListEnv("TRUSTED_PROXIES"),
+		RollupInterval:       getDurationEnv("ROLLUP_INTERVAL", 15*time.Minute),
+
+		VisitQueueSize:     getIntEnv("VISIT_QUEUE_SIZE", 10000),
+		VisitBatchSize:     getIntEnv("VISIT_BATCH_SIZE", 500),
+		VisitFlushInterval: getDurationEnv("VISIT_FLUSH_INTERVAL", time.Second),
+
+		OpenGraphUserAgent:    getEnv("OG_USER_AGENT", "ShortleakBot/1.0 (+link preview)"),
+		OpenGraphTimeout:      getDurationEnv("OG_FETCH_TIMEOUT", 5*time.Second),
+		OpenGraphMaxBodyBytes: getIntEnv("OG_MAX_BODY_BYTES", 1<<20),
+
+		AccessTokenTTL:  getDurationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getDurationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		AppURL:           strings.TrimRight(getEnv("APP_URL", "http://localhost:5173"), "/"),
+		PasswordResetTTL: getDurationEnv("PASSWORD_RESET_TTL", time.Hour),
+
+		EmailVerificationRequired: getBoolEnv("EMAIL_VERIFICATION_REQUIRED", true),
+		EmailVerificationTTL:      getDurationEnv("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailFrom:     getEnv("MAIL_FROM", "Shortleak <no-reply@shortleak.local>"),
+		MailFilePath: getEnv("MAIL_FILE_PATH", ""),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getIntEnv("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		MailTimeout:  getDurationEnv("MAIL_TIMEOUT", 10*time.Second),
 	}
 
 	if cfg.Database == "" {
@@ -57,6 +138,56 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+/** getListEnv splits a comma separated variable, nil when it is unset or empty */
+func getListEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getIntEnv(key string, fallback int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		log.Printf("⚠️ Invalid number for %s: %s", key, value)
+		return fallback
+	}
+	return number
+}
+
+func getBoolEnv(key string, fallback bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("⚠️ Invalid boolean for %s: %s", key, value)
+		return fallback
+	}
+	return enabled
+}
+
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("⚠️ Invalid duration for %s: %v", key, err)
+		return fallback
+	}
+	return duration
+}
+
 func toUpper(s string) string {
 	if len(s) == 0 {
 		return s
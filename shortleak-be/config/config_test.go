@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -75,6 +76,177 @@ func TestLoadConfigMissingDatabaseShouldFatal(t *testing.T) {
 	LoadConfig()
 }
 
+func TestLoadConfigExpiredLinkURL(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DB_DATABASE_DEVELOPMENT", "shortleak-dev")
+	os.Setenv("EXPIRED_LINK_URL", "https://example.com/expired")
+	defer os.Clearenv()
+
+	cfg := LoadConfig()
+
+	assert.Equal(t, "https://example.com/expired", cfg.ExpiredLinkURL)
+}
+
+func TestLoadConfigGeoIPDatabasePath(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DB_DATABASE_DEVELOPMENT", "shortleak-dev")
+	os.Setenv("GEOIP_DB_PATH", "/data/GeoLite2-City.mmdb")
+	defer os.Clearenv()
+
+	cfg := LoadConfig()
+
+	assert.Equal(t, "/data/GeoLite2-City.mmdb", cfg.GeoIPDatabasePath)
+}
+
+func TestLoadConfigRollupInterval(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DB_DATABASE_DEVELOPMENT", "shortleak-dev")
+	defer os.Clearenv()
+
+	assert.Equal(t, 15*time.Minute, LoadConfig().RollupInterval)
+
+	os.Setenv("ROLLUP_INTERVAL", "1h")
+	assert.Equal(t, time.Hour, LoadConfig().RollupInterval)
+
+	// nilai tidak valid pakai default
+	os.Setenv("ROLLUP_INTERVAL", "sometimes")
+	assert.Equal(t, 15*time.Minute, LoadConfig().RollupInterval)
+}
+
+func TestLoadConfigVisitQueue(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DB_DATABASE_DEVELOPMENT", "shortleak-dev")
+	os.Setenv("VISIT_QUEUE_SIZE", "200")
+	os.Setenv("VISIT_BATCH_SIZE", "-5")
+	os.Setenv("VISIT_FLUSH_INTERVAL", "250ms")
+	defer os.Clearenv()
+
+	cfg := LoadConfig()
+
+	assert.Equal(t, 200, cfg.VisitQueueSize)
+	assert.Equal(t, 500, cfg.VisitBatchSize)
+	assert.Equal(t, 250*time.Millisecond, cfg.VisitFlushInterval)
+}
+
+func TestLoadConfigOpenGraph(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DB_DATABASE_DEVELOPMENT", "shortleak-dev")
+	defer os.Clearenv()
+
+	cfg := LoadConfig()
+	assert.Equal(t, "ShortleakBot/1.0 (+link preview)", cfg.OpenGraphUserAgent)
+	assert.Equal(t, 5*time.Second, cfg.OpenGraphTimeout)
+	assert.Equal(t, 1<<20, cfg.OpenGraphMaxBodyBytes)
+
+	os.Setenv("OG_USER_AGENT", "CustomBot/2.0")
+	os.Setenv("OG_FETCH_TIMEOUT", "2s")
+	os.Setenv("OG_MAX_BODY_BYTES", "65536")
+
+	cfg = LoadConfig()
+	assert.Equal(t, "CustomBot/2.0", cfg.OpenGraphUserAgent)
+	assert.Equal(t, 2*time.Second, cfg.OpenGraphTimeout)
+	assert.Equal(t, 65536, cfg.OpenGraphMaxBodyBytes)
+}
+
+func TestLoadConfigDefaultRedirectType(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DB_DATABASE_DEVELOPMENT", "shortleak-dev")
+	defer os.Clearenv()
+
+	assert.Equal(t, 302, LoadConfig().DefaultRedirectType)
+
+	os.Setenv("DEFAULT_REDIRECT_TYPE", "308")
+	assert.Equal(t, 308, LoadConfig().DefaultRedirectType)
+}
+
+func TestLoadConfigTrustedCountryHeader(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DB_DATABASE_DEVELOPMENT", "shortleak-dev")
+	defer os.Clearenv()
+
+	assert.Equal(t, "", LoadConfig().TrustedCountryHeader)
+
+	os.Setenv("TRUSTED_COUNTRY_HEADER", "CF-IPCountry")
+	assert.Equal(t, "CF-IPCountry", LoadConfig().TrustedCountryHeader)
+}
+
+func TestLoadConfigTrustedProxies(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DB_DATABASE_DEVELOPMENT", "shortleak-dev")
+	defer os.Clearenv()
+
+	// default tidak mempercayai proxy mana pun
+	assert.Nil(t, LoadConfig().TrustedProxies)
+
+	os.Setenv("TRUSTED_PROXIES", "10.0.0.1, 172.16.0.0/12,")
+	assert.Equal(t, []string{"10.0.0.1", "172.16.0.0/12"}, LoadConfig().TrustedProxies)
+}
+
+func TestLoadConfigTokenTTL(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DB_DATABASE_DEVELOPMENT", "shortleak-dev")
+	defer os.Clearenv()
+
+	cfg := LoadConfig()
+	assert.Equal(t, 15*time.Minute, cfg.AccessTokenTTL)
+	assert.Equal(t, 30*24*time.Hour, cfg.RefreshTokenTTL)
+
+	os.Setenv("ACCESS_TOKEN_TTL", "5m")
+	os.Setenv("REFRESH_TOKEN_TTL", "168h")
+
+	cfg = LoadConfig()
+	assert.Equal(t, 5*time.Minute, cfg.AccessTokenTTL)
+	assert.Equal(t, 168*time.Hour, cfg.RefreshTokenTTL)
+}
+
+func TestLoadConfigMail(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DB_DATABASE_DEVELOPMENT", "shortleak-dev")
+	defer os.Clearenv()
+
+	cfg := LoadConfig()
+	assert.Equal(t, "http://localhost:5173", cfg.AppURL)
+	assert.Equal(t, time.Hour, cfg.PasswordResetTTL)
+	assert.Equal(t, "log", cfg.MailDriver)
+	assert.Equal(t, 587, cfg.SMTPPort)
+	assert.Equal(t, 10*time.Second, cfg.MailTimeout)
+
+	os.Setenv("APP_URL", "https://short.example.com/")
+	os.Setenv("PASSWORD_RESET_TTL", "30m")
+	os.Setenv("MAIL_DRIVER", "smtp")
+	os.Setenv("SMTP_HOST", "smtp.example.com")
+	os.Setenv("SMTP_PORT", "2525")
+	os.Setenv("MAIL_TIMEOUT", "3s")
+
+	cfg = LoadConfig()
+	assert.Equal(t, "https://short.example.com", cfg.AppURL)
+	assert.Equal(t, 30*time.Minute, cfg.PasswordResetTTL)
+	assert.Equal(t, "smtp", cfg.MailDriver)
+	assert.Equal(t, "smtp.example.com", cfg.SMTPHost)
+	assert.Equal(t, 2525, cfg.SMTPPort)
+	assert.Equal(t, 3*time.Second, cfg.MailTimeout)
+}
+
+func TestLoadConfigEmailVerification(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DB_DATABASE_DEVELOPMENT", "shortleak-dev")
+	defer os.Clearenv()
+
+	cfg := LoadConfig()
+	assert.True(t, cfg.EmailVerificationRequired)
+	assert.Equal(t, 48*time.Hour, cfg.EmailVerificationTTL)
+
+	os.Setenv("EMAIL_VERIFICATION_REQUIRED", "false")
+	os.Setenv("EMAIL_VERIFICATION_TTL", "24h")
+	cfg = LoadConfig()
+	assert.False(t, cfg.EmailVerificationRequired)
+	assert.Equal(t, 24*time.Hour, cfg.EmailVerificationTTL)
+
+	// nilai tidak valid pakai default
+	os.Setenv("EMAIL_VERIFICATION_REQUIRED", "sometimes")
+	assert.True(t, LoadConfig().EmailVerificationRequired)
+}
+
 func TestToUpperEmptyString(t *testing.T) {
 	result := toUpper("")
 	assert.Equal(t, "", result, "expected empty string if input empty")
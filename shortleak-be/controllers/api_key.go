@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"net/http"
+	"shortleak/dto"
+	"shortleak/models"
+	"shortleak/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	createAPIKey = services.CreateAPIKey
+	getAPIKeys   = services.GetAPIKeys
+	getAPIKey    = services.GetAPIKey
+	revokeAPIKey = services.RevokeAPIKey
+)
+
+func GetAPIKeys(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	u := user.(models.User)
+	keys, err := getAPIKeys(u.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+func CreateAPIKey(c *gin.Context) {
+	var req dto.APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	/** Validate name */
+	if req.Name == "" || len(req.Name) > 64 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Name is required and at most 64 characters"})
+		return
+	}
+	/** Validate scopes */
+	if len(req.Scopes) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "At least one scope is required", "scopes": models.APIKeyScopes})
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !models.IsValidAPIKeyScope(scope) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid scope " + scope, "scopes": models.APIKeyScopes})
+			return
+		}
+	}
+	/** Validate expiration */
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Expiration date must be in the future"})
+		return
+	}
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	u := user.(models.User)
+
+	key := models.APIKey{
+		UserID:    u.ID,
+		Name:      req.Name,
+		ExpiresAt: req.ExpiresAt,
+	}
+	raw, err := createAPIKey(&key, req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	/** Create api key log, never with the key itself */
+	if !saveLog(c, u.ID, "create-api-key", map[string]interface{}{
+		"apiKeyId": key.ID,
+		"name":     key.Name,
+		"scopes":   key.ScopeList,
+	}) {
+		return
+	}
+
+	/** The raw key is only returned once */
+	c.JSON(http.StatusCreated, gin.H{
+		"apiKey": key,
+		"key":    raw,
+	})
+}
+
+func RevokeAPIKey(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	u := user.(models.User)
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+	key, err := getAPIKey(u.ID, keyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if err := revokeAPIKey(key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	/** Create revoke log */
+	if !saveLog(c, u.ID, "revoke-api-key", map[string]interface{}{
+		"apiKeyId": key.ID,
+		"name":     key.Name,
+	}) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shortleak/database"
+	"shortleak/models"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAPIKeyInvalidScope(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/api-keys", strings.NewReader(`{"name":"ci","scopes":["links:delete"]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", models.User{ID: uuid.New()})
+
+	CreateAPIKey(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid scope links:delete")
+}
+
+func TestCreateAPIKeyWithoutScopes(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/api-keys", strings.NewReader(`{"name":"ci","scopes":[]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", models.User{ID: uuid.New()})
+
+	CreateAPIKey(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestRevokeAPIKeyInvalidID(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "not-a-uuid"}}
+	c.Request, _ = http.NewRequest("DELETE", "/api/api-keys/not-a-uuid", nil)
+	c.Set("user", models.User{ID: uuid.New()})
+
+	RevokeAPIKey(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	setupTestAuthDB(t)
+	user := models.User{FullName: "John Doe", Email: "john@example.com", Password: "hashed"}
+	database.DB.Create(&user)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", user)
+	})
+	r.GET("/api/api-keys", GetAPIKeys)
+	r.POST("/api/api-keys", CreateAPIKey)
+	r.DELETE("/api/api-keys/:id", RevokeAPIKey)
+
+	req, _ := http.NewRequest("POST", "/api/api-keys", strings.NewReader(`{"name":"ci","scopes":["links:write","stats:read"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created struct {
+		APIKey models.APIKey `json:"apiKey"`
+		Key    string        `json:"key"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.True(t, strings.HasPrefix(created.Key, models.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(created.Key, created.APIKey.Prefix))
+
+	// key mentah tidak disimpan di database
+	var stored models.APIKey
+	database.DB.First(&stored, "id = ?", created.APIKey.ID)
+	assert.NotEqual(t, created.Key, stored.KeyHash)
+	assert.Equal(t, []string{"links:write", "stats:read"}, stored.ScopeList)
+
+	req, _ = http.NewRequest("GET", "/api/api-keys", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.NotContains(t, w.Body.String(), stored.KeyHash)
+
+	req, _ = http.NewRequest("DELETE", "/api/api-keys/"+created.APIKey.ID.String(), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var count int64
+	database.DB.Model(&models.APIKey{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
@@ -1,16 +1,20 @@
 package controllers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"os"
 	"shortleak/database"
 	"shortleak/dto"
 	"shortleak/models"
+	"shortleak/services"
 	"shortleak/utils"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -21,6 +25,70 @@ var signToken = func(token *jwt.Token, secret interface{}) (string, error) {
 	return token.SignedString(secret)
 }
 
+var (
+	createSession      = services.CreateSession
+	refreshSession     = services.RefreshSession
+	findRefreshSession = services.FindRefreshSession
+	revokeSession      = services.RevokeSession
+)
+
+/** accessTokenTTL is the configured access token lifetime, 15 minutes when unset */
+func accessTokenTTL() time.Duration {
+	if AppConfig.AccessTokenTTL > 0 {
+		return AppConfig.AccessTokenTTL
+	}
+	return 15 * time.Minute
+}
+
+/** refreshTokenTTL is the configured session lifetime, 30 days when unset */
+func refreshTokenTTL() time.Duration {
+	if AppConfig.RefreshTokenTTL > 0 {
+		return AppConfig.RefreshTokenTTL
+	}
+	return 30 * 24 * time.Hour
+}
+
+/** refreshCookieName is the cookie holding the refresh token, next to the PLATFORM access token cookie */
+func refreshCookieName() string {
+	return os.Getenv("PLATFORM") + "_refresh"
+}
+
+/** signAccessToken creates a short lived JWT for a session */
+func signAccessToken(session models.Session, now time.Time) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userId":    session.UserID,
+		"sessionId": session.ID,
+		"exp":       now.Add(accessTokenTTL()).Unix(),
+	})
+	return signToken(token, jwtSecret)
+}
+
+/** setAuthCookies stores the tokens for browsers, the refresh token is only sent to the auth routes */
+func setAuthCookies(c *gin.Context, accessToken, refreshToken string) {
+	c.SetCookie(os.Getenv("PLATFORM"), accessToken, int(accessTokenTTL().Seconds()), "/", "", false, true)
+	c.SetCookie(refreshCookieName(), refreshToken, int(refreshTokenTTL().Seconds()), "/api/auth", "", false, true)
+}
+
+func clearAuthCookies(c *gin.Context) {
+	c.SetCookie(os.Getenv("PLATFORM"), "", -1, "/", "", false, true)
+	c.SetCookie(refreshCookieName(), "", -1, "/api/auth", "", false, true)
+}
+
+/** refreshTokenFromRequest reads the refresh token from the JSON body or else the cookie, ok is false for a malformed body */
+func refreshTokenFromRequest(c *gin.Context) (string, bool) {
+	var req dto.RefreshRequest
+	if c.Request.Body != nil && c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return "", false
+		}
+	}
+	if req.RefreshToken != "" {
+		return req.RefreshToken, true
+	}
+	cookie, _ := c.Cookie(refreshCookieName())
+	return cookie, true
+}
+
 /** Register a new user */
 func Register(c *gin.Context) {
 	var req dto.RegisterRequest
@@ -44,6 +112,7 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	var user models.User
 	err := database.DB.Transaction(func(tx *gorm.DB) error {
 		/** Hash the password */
 		hashed, err := generatePasswordHash([]byte(req.Password), 10)
@@ -51,8 +120,8 @@ func Register(c *gin.Context) {
 			return err
 		}
 
-		/** Create a new user */
-		user := models.User{
+		/** Create a new user, unverified until the verification link is opened */
+		user = models.User{
 			FullName: req.FullName,
 			Email:    req.Email,
 			Password: string(hashed),
@@ -83,13 +152,17 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	/** The link can be resent, a failed mail does not fail the registration */
+	if err := sendEmailVerification(user); err != nil {
+		log.Println("❌ Failed to send verification email:", err)
+	}
+
 	/** Return success */
 	c.JSON(http.StatusOK, gin.H{"message": "User registered successfully"})
 }
 
 /** Login user */
 func Login(c *gin.Context) {
-	platform := os.Getenv("PLATFORM")
 	/** Validate request body */
 	var req dto.LoginRequest
 
@@ -130,39 +203,104 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	/** Create JWT token */
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userId": user.ID,
-		"exp":    time.Now().Add(time.Hour * 24).Unix(), /** expired 1 day */
-	})
-
-	/** Sign the token with secret key */
-	tokenString, err := signToken(token, jwtSecret)
+	/** Start a session for this device, the access token names it so logging out revokes the token */
+	now := time.Now()
+	session := models.Session{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+	tokenString, err := signAccessToken(session, now)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
 		return
 	}
+	refreshToken, err := createSession(&session, refreshTokenTTL(), now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
 
-	/** Set token in cookie */
-	c.SetCookie(platform, tokenString, 3600*24, "/", "", false, true)
+	/** Set tokens in cookie */
+	setAuthCookies(c, tokenString, refreshToken)
 
 	var dataUser = map[string]interface{}{
-		"id":       user.ID,
-		"fullname": user.FullName,
-		"email":    user.Email,
+		"id":            user.ID,
+		"fullname":      user.FullName,
+		"email":         user.Email,
+		"emailVerified": user.IsEmailVerified(),
 	}
 
 	/** Return success */
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   tokenString,
-		"user":    dataUser,
+		"message":      "Login successful",
+		"token":        tokenString,
+		"refreshToken": refreshToken,
+		"expiresIn":    int(accessTokenTTL().Seconds()),
+		"user":         dataUser,
+	})
+}
+
+/** Refresh exchanges a refresh token for a new access token and a new refresh token */
+func Refresh(c *gin.Context) {
+	raw, ok := refreshTokenFromRequest(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if raw == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token is required"})
+		return
+	}
+
+	now := time.Now()
+	session, refreshToken, err := refreshSession(raw, refreshTokenTTL(), now)
+	if errors.Is(err, services.ErrInvalidRefreshToken) {
+		clearAuthCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	tokenString, err := signAccessToken(*session, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	setAuthCookies(c, tokenString, refreshToken)
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Token refreshed",
+		"token":        tokenString,
+		"refreshToken": refreshToken,
+		"expiresIn":    int(accessTokenTTL().Seconds()),
 	})
 }
 
-/** Logout user */
+/** Logout revokes the session of the request and clears the token cookies */
 func Logout(c *gin.Context) {
-	/** Clear the token cookie */
-	c.SetCookie("token", "", -1, "/", "", false, true)
+	var sessionID uuid.UUID
+
+	/** The refresh token still identifies the session after the access token expired */
+	if raw, _ := refreshTokenFromRequest(c); raw != "" {
+		if session, err := findRefreshSession(raw); err == nil {
+			sessionID = session.ID
+		}
+	}
+	if value, exists := c.Get("session"); exists && sessionID == uuid.Nil {
+		sessionID = value.(models.Session).ID
+	}
+	if sessionID != uuid.Nil {
+		if err := revokeSession(sessionID, time.Now()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+			return
+		}
+	}
+
+	clearAuthCookies(c)
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
 }
@@ -9,9 +9,11 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"shortleak/database"
 	"shortleak/models"
+	"shortleak/services"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
@@ -35,13 +37,13 @@ func setupTestAuthDB(t *testing.T) {
 	}
 
 	// bersihkan tabel agar fresh
-	err = db.Migrator().DropTable(&models.User{}, &models.Log{})
+	err = db.Migrator().DropTable(&models.User{}, &models.Log{}, &models.APIKey{}, &models.Session{}, &models.PasswordResetToken{})
 	if err != nil {
 		t.Fatalf("failed to drop tables: %v", err)
 	}
 
 	// migrasi ulang tabel
-	err = db.AutoMigrate(&models.User{}, &models.Log{})
+	err = db.AutoMigrate(&models.User{}, &models.Log{}, &models.APIKey{}, &models.Session{}, &models.PasswordResetToken{})
 	if err != nil {
 		t.Fatalf("failed to migrate test DB: %v", err)
 	}
@@ -199,7 +201,7 @@ func TestRegisterDBInsertLogFails(t *testing.T) {
 
 	// Insert user sukses (pakai Query RETURNING id)
 	mock.ExpectQuery(`INSERT INTO "users"`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "John", "johnlogfail@example.com", sqlmock.AnyArg(), true, sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "John", "johnlogfail@example.com", sqlmock.AnyArg(), true, models.RoleUser, nil, sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("f466fb51-1aff-46c0-bfaf-d3e3931582c9"))
 
 	// Insert log gagal
@@ -283,6 +285,12 @@ func TestLoginSuccess(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "Login successful")
 	assert.Contains(t, w.Body.String(), "token")
+	assert.Contains(t, w.Body.String(), "refreshToken")
+
+	// login membuat session baru
+	var count int64
+	database.DB.Model(&models.Session{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
 }
 
 func TestLoginInvalidJSON(t *testing.T) {
@@ -484,6 +492,7 @@ func TestLoginUserNotFound(t *testing.T) {
 }
 
 func TestLogout(t *testing.T) {
+	t.Setenv("PLATFORM", "shortleak")
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 	r.POST("/logout", Logout)
@@ -496,13 +505,143 @@ func TestLogout(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "Logged out")
 
-	// pastikan cookie token terhapus
-	cookies := w.Result().Cookies()
-	found := false
-	for _, c := range cookies {
-		if c.Name == "token" && c.Value == "" && c.MaxAge < 0 {
-			found = true
+	// pastikan cookie access token dan refresh token terhapus
+	cleared := map[string]bool{}
+	for _, c := range w.Result().Cookies() {
+		if c.Value == "" && c.MaxAge < 0 {
+			cleared[c.Name] = true
+		}
+	}
+	assert.True(t, cleared["shortleak"], "Logout should clear the PLATFORM cookie")
+	assert.True(t, cleared["shortleak_refresh"], "Logout should clear the refresh token cookie")
+}
+
+func TestLogoutRevokesRefreshTokenSession(t *testing.T) {
+	t.Setenv("PLATFORM", "shortleak")
+	session := models.Session{ID: uuid.New(), UserID: uuid.New()}
+	var revoked []uuid.UUID
+	origFind, origRevoke := findRefreshSession, revokeSession
+	findRefreshSession = func(raw string) (*models.Session, error) {
+		if raw != "refresh-token" {
+			return nil, errors.New("record not found")
+		}
+		return &session, nil
+	}
+	revokeSession = func(sessionID uuid.UUID, now time.Time) error {
+		revoked = append(revoked, sessionID)
+		return nil
+	}
+	defer func() { findRefreshSession, revokeSession = origFind, origRevoke }()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/logout", Logout)
+
+	req, _ := http.NewRequest("POST", "/logout", nil)
+	req.AddCookie(&http.Cookie{Name: "shortleak_refresh", Value: "refresh-token"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []uuid.UUID{session.ID}, revoked)
+}
+
+func TestLogoutRevokesCurrentSession(t *testing.T) {
+	session := models.Session{ID: uuid.New(), UserID: uuid.New()}
+	var revoked []uuid.UUID
+	origRevoke := revokeSession
+	revokeSession = func(sessionID uuid.UUID, now time.Time) error {
+		revoked = append(revoked, sessionID)
+		return nil
+	}
+	defer func() { revokeSession = origRevoke }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/auth/logout", nil)
+	c.Set("session", session)
+
+	Logout(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []uuid.UUID{session.ID}, revoked)
+}
+
+func TestRefreshWithoutToken(t *testing.T) {
+	t.Setenv("PLATFORM", "shortleak")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/auth/refresh", nil)
+
+	Refresh(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Refresh token is required")
+}
+
+func TestRefreshInvalidToken(t *testing.T) {
+	t.Setenv("PLATFORM", "shortleak")
+	origRefresh := refreshSession
+	refreshSession = func(raw string, ttl time.Duration, now time.Time) (*models.Session, string, error) {
+		return nil, "", services.ErrInvalidRefreshToken
+	}
+	defer func() { refreshSession = origRefresh }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/auth/refresh", strings.NewReader(`{"refresh_token":"reused"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	Refresh(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid refresh token")
+}
+
+func TestRefreshSuccess(t *testing.T) {
+	t.Setenv("PLATFORM", "shortleak")
+	session := models.Session{ID: uuid.New(), UserID: uuid.New()}
+	origRefresh := refreshSession
+	refreshSession = func(raw string, ttl time.Duration, now time.Time) (*models.Session, string, error) {
+		if raw != "old-refresh-token" {
+			return nil, "", services.ErrInvalidRefreshToken
 		}
+		return &session, "new-refresh-token", nil
+	}
+	defer func() { refreshSession = origRefresh }()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/api/auth/refresh", Refresh)
+
+	req, _ := http.NewRequest("POST", "/api/auth/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: "shortleak_refresh", Value: "old-refresh-token"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refreshToken"`
+		ExpiresIn    int    `json:"expiresIn"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	assert.Equal(t, "new-refresh-token", body.RefreshToken)
+	assert.Equal(t, 900, body.ExpiresIn)
+
+	// access token baru membawa id session
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(body.Token, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, session.ID.String(), claims["sessionId"])
+
+	cookies := map[string]*http.Cookie{}
+	for _, c := range w.Result().Cookies() {
+		cookies[c.Name] = c
 	}
-	assert.True(t, found, "Logout should clear token cookie")
+	assert.Equal(t, body.Token, cookies["shortleak"].Value)
+	assert.Equal(t, "new-refresh-token", cookies["shortleak_refresh"].Value)
+	assert.Equal(t, "/api/auth", cookies["shortleak_refresh"].Path)
 }
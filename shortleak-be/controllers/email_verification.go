@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"shortleak/dto"
+	"shortleak/models"
+	"shortleak/services"
+	"shortleak/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	getUser         = services.GetUser
+	verifyUserEmail = services.VerifyUserEmail
+)
+
+/** emailVerificationPurpose marks verification tokens so they cannot be used as access tokens, or the reverse */
+const emailVerificationPurpose = "verify-email"
+
+/** verificationResendLimiter bounds the verification mails one user can ask for */
+var verificationResendLimiter = utils.NewAttemptLimiter(3, time.Hour)
+
+/** emailVerificationTTL is the configured verification link lifetime, two days when unset */
+func emailVerificationTTL() time.Duration {
+	if AppConfig.EmailVerificationTTL > 0 {
+		return AppConfig.EmailVerificationTTL
+	}
+	return 48 * time.Hour
+}
+
+/** signEmailVerificationToken signs a token for the current email of the user, changing the email voids it */
+func signEmailVerificationToken(user models.User, now time.Time) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userId":  user.ID,
+		"email":   user.Email,
+		"purpose": emailVerificationPurpose,
+		"exp":     now.Add(emailVerificationTTL()).Unix(),
+	})
+	return signToken(token, jwtSecret)
+}
+
+/** parseEmailVerificationToken returns the user and email a verification token was signed for */
+func parseEmailVerificationToken(raw string) (uuid.UUID, string, bool) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid || claims["purpose"] != emailVerificationPurpose {
+		return uuid.Nil, "", false
+	}
+	userID, _ := claims["userId"].(string)
+	email, _ := claims["email"].(string)
+	id, err := uuid.Parse(userID)
+	if err != nil || email == "" {
+		return uuid.Nil, "", false
+	}
+	return id, email, true
+}
+
+func emailVerificationMail(user models.User, link string, ttl time.Duration) utils.MailMessage {
+	return utils.MailMessage{
+		To:      user.Email,
+		Subject: "Verify your Shortleak email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Please confirm that this is your email address by opening the link below within %s:\n\n%s\n\n"+
+			"If you did not create a Shortleak account, you can ignore this email.\n",
+			user.FullName, ttl, link),
+	}
+}
+
+/** sendEmailVerification mails a verification link to the current email of the user in the background */
+func sendEmailVerification(user models.User) error {
+	ttl := emailVerificationTTL()
+	raw, err := signEmailVerificationToken(user, time.Now())
+	if err != nil {
+		return err
+	}
+	link := appURL() + "/verify-email?token=" + url.QueryEscape(raw)
+	sendMail(emailVerificationMail(user, link, ttl))
+	return nil
+}
+
+/** requireVerifiedEmail rejects users who did not verify their email yet, when verification is required */
+func requireVerifiedEmail(c *gin.Context, user models.User) bool {
+	if !AppConfig.EmailVerificationRequired || user.IsEmailVerified() {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "Verify your email address before creating links"})
+	return false
+}
+
+/** VerifyEmail marks the email of a user verified with the token of a verification link */
+func VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if errors := utils.ValidateStruct(req); errors != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_errors": errors})
+		return
+	}
+
+	userID, email, ok := parseEmailVerificationToken(req.Token)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+	user, err := getUser(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+	if user.IsEmailVerified() {
+		c.JSON(http.StatusOK, gin.H{"message": "Email already verified"})
+		return
+	}
+	/** A link sent before the email changed does not verify the new address */
+	if user.Email != email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	verified, err := verifyUserEmail(user.ID, email, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+	if !verified {
+		c.JSON(http.StatusOK, gin.H{"message": "Email already verified"})
+		return
+	}
+
+	if !saveLog(c, user.ID, "verify-email", map[string]interface{}{
+		"email": email,
+	}) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+/** ResendEmailVerification mails a new verification link to the authenticated user */
+func ResendEmailVerification(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	u := user.(models.User)
+	if u.IsEmailVerified() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	limiterKey := u.ID.String()
+	if verificationResendLimiter.Blocked(limiterKey) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many verification emails, try again later"})
+		return
+	}
+	verificationResendLimiter.Fail(limiterKey)
+
+	if err := sendEmailVerification(u); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	if !saveLog(c, u.ID, "resend-email-verification", map[string]interface{}{
+		"email": u.Email,
+	}) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
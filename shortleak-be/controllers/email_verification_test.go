@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"shortleak/database"
+	"shortleak/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailVerificationToken(t *testing.T) {
+	user := models.User{ID: uuid.New(), Email: "john@example.com"}
+	now := time.Now()
+
+	raw, err := signEmailVerificationToken(user, now)
+	assert.NoError(t, err)
+	userID, email, ok := parseEmailVerificationToken(raw)
+	assert.True(t, ok)
+	assert.Equal(t, user.ID, userID)
+	assert.Equal(t, user.Email, email)
+
+	// token kadaluarsa ditolak
+	expired, _ := signEmailVerificationToken(user, now.Add(-72*time.Hour))
+	_, _, ok = parseEmailVerificationToken(expired)
+	assert.False(t, ok)
+
+	// access token tidak bisa dipakai sebagai token verifikasi
+	access, _ := signAccessToken(models.Session{ID: uuid.New(), UserID: user.ID}, now)
+	_, _, ok = parseEmailVerificationToken(access)
+	assert.False(t, ok)
+}
+
+func mockGetUser(t *testing.T, user models.User) {
+	orig := getUser
+	getUser = func(userID uuid.UUID) (*models.User, error) {
+		found := user
+		return &found, nil
+	}
+	t.Cleanup(func() { getUser = orig })
+}
+
+func serveVerifyEmail(token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/api/auth/verify-email", VerifyEmail)
+	return postJSON(r, "/api/auth/verify-email", `{"token":"`+token+`"}`)
+}
+
+func TestVerifyEmailInvalidToken(t *testing.T) {
+	w := serveVerifyEmail("not-a-token")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid or expired verification link")
+}
+
+func TestVerifyEmailChangedAddress(t *testing.T) {
+	user := models.User{ID: uuid.New(), Email: "old@example.com"}
+	token, _ := signEmailVerificationToken(user, time.Now())
+	user.Email = "new@example.com"
+	mockGetUser(t, user)
+
+	w := serveVerifyEmail(token)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestVerifyEmailAlreadyVerified(t *testing.T) {
+	verifiedAt := time.Now()
+	user := models.User{ID: uuid.New(), Email: "john@example.com", EmailVerifiedAt: &verifiedAt}
+	token, _ := signEmailVerificationToken(user, time.Now())
+	mockGetUser(t, user)
+
+	w := serveVerifyEmail(token)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Email already verified")
+}
+
+func TestResendEmailVerificationAlreadyVerified(t *testing.T) {
+	mailer := mockMailer(t)
+	verifiedAt := time.Now()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/auth/verify-email/resend", nil)
+	c.Set("user", models.User{ID: uuid.New(), EmailVerifiedAt: &verifiedAt})
+
+	ResendEmailVerification(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, mailer.sent)
+}
+
+func TestCreateLinkRequiresVerifiedEmail(t *testing.T) {
+	origConfig := AppConfig
+	AppConfig.EmailVerificationRequired = true
+	defer func() { AppConfig = origConfig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/shorten", strings.NewReader(`{"url":"https://example.com"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", models.User{ID: uuid.New()})
+
+	CreateLink(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Verify your email address")
+}
+
+func TestEmailVerificationFlow(t *testing.T) {
+	setupTestAuthDB(t)
+	mailer := mockMailer(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/api/auth/register", Register)
+	r.POST("/api/auth/verify-email", VerifyEmail)
+
+	w := postJSON(r, "/api/auth/register", `{"fullname":"John Doe","email":"john@example.com","password":"Secret123!"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// akun baru belum terverifikasi
+	var user models.User
+	database.DB.First(&user, "email = ?", "john@example.com")
+	assert.False(t, user.IsEmailVerified())
+
+	WaitForMail()
+	assert.Len(t, mailer.sent, 1)
+	link, _ := url.Parse(regexp.MustCompile(`https?://\S+`).FindString(mailer.sent[0].Body))
+	token := link.Query().Get("token")
+
+	w = postJSON(r, "/api/auth/verify-email", `{"token":"`+token+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Email verified")
+
+	database.DB.First(&user, "id = ?", user.ID)
+	assert.True(t, user.IsEmailVerified())
+
+	var logs int64
+	database.DB.Model(&models.Log{}).Where("user_id = ? AND action = ?", user.ID, "verify-email").Count(&logs)
+	assert.Equal(t, int64(1), logs)
+}
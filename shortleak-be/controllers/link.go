@@ -1,39 +1,268 @@
 package controllers
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
+	"shortleak/config"
 	"shortleak/database"
 	"shortleak/dto"
 	"shortleak/models"
 	"shortleak/services"
 	"shortleak/utils"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"gorm.io/datatypes"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 var getLinksByUserID = services.GetLinksByUserID
 var createLink = services.CreateLink
 var deleteLink = services.DeleteLink
+var setLinkActive = services.SetLinkActive
+var claimLinkClick = services.ClaimLinkClick
+var updateLinkURL = services.UpdateLinkURL
+var getLinkHistory = services.GetLinkHistory
+var updateLinkOpenGraph = services.UpdateLinkOpenGraph
+var updateLinkRedirectOptions = services.UpdateLinkRedirectOptions
 var (
 	getLinkByShortToken = services.GetLinkByShortToken
 	getOpenGraphData    = utils.GetOpenGraphData
 )
-var countUniqueVisitors = func(shortToken string) (int64, error) {
-	var uniqueVisitors int64
-	err := database.DB.Model(&models.Log{}).
-		Select("COUNT(DISTINCT(user_id))").
-		Where("action = ?", "visit-link").
-		Where("data->>'shortToken' = ?", shortToken).
-		Scan(&uniqueVisitors).Error
-	return uniqueVisitors, err
+var countVisits = func(linkID uuid.UUID) (int64, error) {
+	tail, err := visitTailStart(linkID)
+	if err != nil {
+		return 0, err
+	}
+	var rolledUp, live int64
+	err = database.DB.Model(&models.VisitDailyRollup{}).
+		Select("CAST(COALESCE(SUM(visits), 0) AS bigint)").
+		Where("link_id = ?", linkID).
+		Scan(&rolledUp).Error
+	if err != nil {
+		return 0, err
+	}
+	err = database.DB.Model(&models.Visit{}).
+		Where("link_id = ? AND created_at >= ?", linkID, tail).
+		Count(&live).Error
+	return rolledUp + live, err
+}
+var countUniqueVisitors = func(linkID uuid.UUID) (int64, error) {
+	tail, err := visitTailStart(linkID)
+	if err != nil {
+		return 0, err
+	}
+	/** Rollups store first-time visitors per day, the live tail only adds clients not seen before it */
+	var rolledUp, live int64
+	err = database.DB.Model(&models.VisitDailyRollup{}).
+		Select("CAST(COALESCE(SUM(new_visitors), 0) AS bigint)").
+		Where("link_id = ?", linkID).
+		Scan(&rolledUp).Error
+	if err != nil {
+		return 0, err
+	}
+	err = database.DB.Model(&models.Visit{}).
+		Select("COUNT(DISTINCT(client_id))").
+		Where("link_id = ? AND created_at >= ?", linkID, tail).
+		Where("NOT EXISTS (SELECT 1 FROM visits seen WHERE seen.link_id = visits.link_id AND seen.client_id = visits.client_id AND seen.created_at < ? AND seen.deleted_at IS NULL)", tail).
+		Scan(&live).Error
+	return rolledUp + live, err
 }
 var Validator utils.Validator = utils.DefaultValidator{}
 var DB *gorm.DB
 
+/** AppConfig holds the server configuration, set by server.SetupRouter */
+var AppConfig config.Config
+
+/** GeoIP resolves visitor locations, nil when no database is configured */
+var GeoIP *utils.GeoIP
+
+/** VisitRecorder queues visits for the background writer, set by server.SetupRouter */
+var VisitRecorder *services.VisitRecorder
+
+/** recordVisit hands a visit to the recorder without waiting for the database */
+var recordVisit = func(event services.VisitEvent) bool {
+	return VisitRecorder.Record(event)
+}
+
+/** lookupGeo geolocates a visitor IP, empty values when GeoIP is disabled */
+var lookupGeo = func(ip string) utils.GeoLocation {
+	return GeoIP.Lookup(ip)
+}
+
+var (
+	/** linkPasswordLimiter blocks an IP address after too many wrong passwords for a link */
+	linkPasswordLimiter = utils.NewAttemptLimiter(5, 15*time.Minute)
+	/** linkPasswordTotalLimiter caps the wrong passwords of a link from all addresses together */
+	linkPasswordTotalLimiter = utils.NewAttemptLimiter(50, 15*time.Minute)
+)
+
+/** checkLinkPassword verifies the password sent via header or form, rate limited per IP address and per link */
+func checkLinkPassword(c *gin.Context, link *models.Link) bool {
+	/** Not the client_id cookie, a client without it gets a new one and would never be blocked */
+	key := c.ClientIP() + ":" + link.ShortToken
+	if linkPasswordLimiter.Blocked(key) || linkPasswordTotalLimiter.Blocked(link.ShortToken) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed password attempts, try again later"})
+		return false
+	}
+	password := c.GetHeader("X-Link-Password")
+	if password == "" {
+		password = c.PostForm("password")
+	}
+	if password == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password required", "password_required": true})
+		return false
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(link.Password), []byte(password)); err != nil {
+		linkPasswordLimiter.Fail(key)
+		linkPasswordTotalLimiter.Fail(link.ShortToken)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password", "password_required": true})
+		return false
+	}
+	linkPasswordLimiter.Reset(key)
+	return true
+}
+
+/** newVisit builds the visit record of a redirect from the request metadata */
+func newVisit(c *gin.Context, linkID uuid.UUID, clientID uuid.UUID) models.Visit {
+	referrer := c.Request.Referer()
+	userAgent := c.Request.UserAgent()
+	acceptLanguage := c.GetHeader("Accept-Language")
+	ua := utils.ParseUserAgent(userAgent)
+	geo := lookupGeo(c.ClientIP())
+	/** A trusted proxy header wins over the local database for the country */
+	if country := trustedCountry(c); country != "" {
+		geo.Country = country
+	}
+	return models.Visit{
+		ID:             uuid.New(),
+		CreatedAt:      time.Now(),
+		LinkID:         linkID,
+		ClientID:       clientID,
+		Referrer:       referrer,
+		ReferrerDomain: utils.ReferrerDomain(referrer),
+		UserAgent:      userAgent,
+		Browser:        ua.Browser,
+		OS:             ua.OS,
+		Device:         ua.Device,
+		AcceptLanguage: acceptLanguage,
+		Language:       utils.PrimaryLanguage(acceptLanguage),
+		Country:        geo.Country,
+		Region:         geo.Region,
+		City:           geo.City,
+	}
+}
+
+/** trustedCountry reads the visitor country from the configured proxy header, empty when unset or unknown */
+func trustedCountry(c *gin.Context) string {
+	if AppConfig.TrustedCountryHeader == "" {
+		return ""
+	}
+	country := strings.ToUpper(strings.TrimSpace(c.GetHeader(AppConfig.TrustedCountryHeader)))
+	/** Cloudflare sends XX for unknown and T1 for Tor */
+	if len(country) != 2 || country == "XX" || country == "T1" {
+		return ""
+	}
+	return country
+}
+
+/** fetchOpenGraph scrapes the link destination and caches its preview on the link */
+func fetchOpenGraph(link *models.Link) error {
+	og, err := getOpenGraphData(link.URL)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	link.OGTitle = og.Title
+	link.OGDescription = og.Description
+	link.OGSiteName = og.SiteName
+	link.OGImage = ""
+	if len(og.Images) > 0 {
+		link.OGImage = og.Images[0].SecureURL
+		if link.OGImage == "" {
+			link.OGImage = og.Images[0].URL
+		}
+	}
+	link.OGFetchedAt = &now
+	return nil
+}
+
+/** redirectStatus picks the status code of a redirect, the link type first and then the server default */
+func redirectStatus(c *gin.Context, link *models.Link) int {
+	/** A password posted by form must not be replayed to the destination by 307/308 */
+	if link.Password != "" && c.Request.Method == http.MethodPost {
+		return http.StatusSeeOther
+	}
+	if models.IsValidRedirectType(link.RedirectType) {
+		return link.RedirectType
+	}
+	if models.IsValidRedirectType(AppConfig.DefaultRedirectType) {
+		return AppConfig.DefaultRedirectType
+	}
+	return http.StatusFound
+}
+
+/** forwardedPath returns the path after the short token, ok is false when the link does not forward paths */
+func forwardedPath(c *gin.Context, link *models.Link) (string, bool) {
+	extraPath := c.Param("path")
+	if extraPath == "/" {
+		extraPath = ""
+	}
+	return extraPath, extraPath == "" || link.ForwardPath
+}
+
+/** destinationURL applies the forwarding options of a link to destination */
+func destinationURL(c *gin.Context, link *models.Link, destination, extraPath string) (string, error) {
+	var query url.Values
+	if link.ForwardQuery {
+		query = c.Request.URL.Query()
+	}
+	if extraPath == "" && len(query) == 0 {
+		return destination, nil
+	}
+	return utils.ForwardURL(destination, extraPath, query)
+}
+
+/** authorizeLink loads the link from the path and checks the authenticated user may manage it */
+func authorizeLink(c *gin.Context) (*models.Link, models.User, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, models.User{}, false
+	}
+	u := user.(models.User)
+	link, err := getLinkByShortToken(c.Param("shortToken"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+		return nil, u, false
+	}
+	if !services.CanManageLink(u, link) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return nil, u, false
+	}
+	return link, u, true
+}
+
+/** isLinkExpired checks whether the link passed its expiration date or used up its clicks when it was loaded */
+func isLinkExpired(link *models.Link) bool {
+	if link.ExpiresAt != nil && !time.Now().Before(*link.ExpiresAt) {
+		return true
+	}
+	return link.MaxClicks != nil && link.Clicks >= *link.MaxClicks
+}
+
+/** respondLinkExpired sends an expired link to the configured page, or answers 410 */
+func respondLinkExpired(c *gin.Context) {
+	if AppConfig.ExpiredLinkURL != "" {
+		c.Redirect(http.StatusFound, AppConfig.ExpiredLinkURL)
+		return
+	}
+	c.JSON(http.StatusGone, gin.H{"error": "Link has expired"})
+}
+
 func GetLinksByUserAuth(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -56,7 +285,30 @@ func GetLinkByShortToken(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
 		return
 	}
-	c.JSON(http.StatusOK, link)
+	if !link.Active {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Link is inactive"})
+		return
+	}
+	/** The route is public, so the owner and the link settings are left out */
+	response := dto.PublicLinkResponse{
+		ShortToken:        link.ShortToken,
+		PasswordProtected: link.PasswordProtected,
+	}
+	/** Do not reveal the destination or its preview for password protected links */
+	if !link.PasswordProtected {
+		response.URL = link.URL
+		response.OGTitle = link.OGTitle
+		response.OGDescription = link.OGDescription
+		response.OGImage = link.OGImage
+		response.OGSiteName = link.OGSiteName
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+/** hasLinkOptions reports whether the request sets anything an existing link for the same URL might not have */
+func hasLinkOptions(req dto.LinkRequest) bool {
+	return req.Password != "" || req.ExpiresAt != nil || req.MaxClicks != nil ||
+		req.RedirectType != 0 || req.ForwardQuery || req.ForwardPath
 }
 
 func CreateLink(c *gin.Context) {
@@ -82,47 +334,114 @@ func CreateLink(c *gin.Context) {
 		return
 	}
 	u := user.(models.User)
-	/** Check if link already exists */
-	var existingLink models.Link
-	if err := database.DB.First(&existingLink, "url = ?", req.URL).Error; err == nil {
-		c.JSON(http.StatusOK, gin.H{"shortToken": existingLink.ShortToken})
+	if !requireVerifiedEmail(c, u) {
 		return
 	}
-	/** Generate unique code */
-	shortToken := utils.GenerateRandomString(5)
-	for {
+	/** Validate expiration */
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Expiration date must be in the future"})
+		return
+	}
+	if req.MaxClicks != nil && *req.MaxClicks < 1 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Max clicks must be at least 1"})
+		return
+	}
+	/** Validate link password */
+	if req.Password != "" && len(req.Password) < 4 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Password must be at least 4 characters long"})
+		return
+	}
+	/** Validate redirect type */
+	if req.RedirectType != 0 && !models.IsValidRedirectType(req.RedirectType) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Redirect type must be one of 301, 302, 307 or 308"})
+		return
+	}
+	/** Validate custom alias */
+	if req.Alias != "" {
+		if err := utils.ValidateAliasDirect(req.Alias); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid alias", "details": err.Error()})
+			return
+		}
+	}
+	/** Check if the user already shortened this URL, unless a new link or link options are requested */
+	if req.Alias == "" && !req.AllowDuplicate && !hasLinkOptions(req) {
 		var existingLink models.Link
-		if err := database.DB.First(&existingLink, "short_token = ?", shortToken).Error; err != nil {
-			break
+		if err := database.DB.First(&existingLink, "user_id = ? AND url = ?", u.ID, req.URL).Error; err == nil {
+			c.JSON(http.StatusOK, gin.H{"shortToken": existingLink.ShortToken})
+			return
+		}
+	}
+	var shortToken string
+	if req.Alias != "" {
+		/** Check if alias is already taken, including soft deleted links */
+		var takenLink models.Link
+		if err := database.DB.Unscoped().First(&takenLink, "short_token = ?", req.Alias).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Alias is already taken"})
+			return
 		}
+		shortToken = req.Alias
+	} else {
+		/** Generate unique code */
 		shortToken = utils.GenerateRandomString(5)
+		for {
+			var existingLink models.Link
+			if err := database.DB.First(&existingLink, "short_token = ?", shortToken).Error; err != nil {
+				break
+			}
+			shortToken = utils.GenerateRandomString(5)
+		}
+	}
+	/** Hash the link password */
+	var hashedPassword string
+	if req.Password != "" {
+		hashed, err := generatePasswordHash([]byte(req.Password), 10)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		hashedPassword = string(hashed)
 	}
 	/** Create link */
 	var link = models.Link{
-		URL:        req.URL,
-		UserID:     u.ID,
-		ShortToken: shortToken,
+		URL:          req.URL,
+		UserID:       u.ID,
+		ShortToken:   shortToken,
+		ExpiresAt:    req.ExpiresAt,
+		MaxClicks:    req.MaxClicks,
+		Password:     hashedPassword,
+		RedirectType: req.RedirectType,
+		ForwardQuery: req.ForwardQuery,
+		ForwardPath:  req.ForwardPath,
 	}
+	/** Cache the preview once, a destination without OpenGraph data can still be shortened */
+	_ = fetchOpenGraph(&link)
 	if err := createLink(&link); err != nil {
+		/** Another request took the token after the checks above */
+		if errors.Is(err, services.ErrShortTokenTaken) {
+			message := "Short link already taken, try again"
+			if req.Alias != "" {
+				message = "Alias is already taken"
+			}
+			c.JSON(http.StatusConflict, gin.H{"error": message})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	/** Create link log */
-	b, _ := json.Marshal(link)
-	log := models.Log{
-		UserID: u.ID,
-		Action: "create-link",
-		Data:   datatypes.JSON(b),
-	}
-
-	/** Save log to database */
-	if err := database.DB.Create(&log).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if !saveLog(c, u.ID, "create-link", link) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"shortToken": link.ShortToken})
+	c.JSON(http.StatusCreated, gin.H{
+		"shortToken":    link.ShortToken,
+		"expires_at":    link.ExpiresAt,
+		"max_clicks":    link.MaxClicks,
+		"redirect_type": link.RedirectType,
+		"forward_query": link.ForwardQuery,
+		"forward_path":  link.ForwardPath,
+	})
 }
 
 func RedirectLink(c *gin.Context) {
@@ -132,60 +451,102 @@ func RedirectLink(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
 		return
 	}
-	/** Get Open Graph Data */
-	ogData, err := getOpenGraphData(link.URL)
+	/** Check if link is active */
+	if !link.Active {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Link is inactive"})
+		return
+	}
+	/** Check if link has expired */
+	if isLinkExpired(link) {
+		respondLinkExpired(c)
+		return
+	}
+	/** A path after the short token is only accepted when the link forwards it */
+	extraPath, ok := forwardedPath(c, link)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+		return
+	}
+	/** Check link password */
+	if link.Password != "" && !checkLinkPassword(c, link) {
+		return
+	}
+	/** Get client id from context, ClientIDMiddleware sets it even before the visitor has the cookie */
+	uID, err := uuid.Parse(c.GetString("client_id"))
+	if err != nil {
+		uID = uuid.New()
+	}
+	visit := newVisit(c, link.ID, uID)
+	/** Pick the destination, a matching targeting rule wins over the weighted variants and the link URL */
+	rules, err := getLinkRules(link.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	/** Get client id from context */
-	clientID, _ := c.Cookie("client_id")
-	uID, err := uuid.Parse(clientID)
+	variants, err := getLinkVariants(link.ID)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid client ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	/** Create log entry */
-	payload := map[string]interface{}{
-		"ogData":     ogData,
-		"shortToken": shortToken,
-	}
-
-	b, _ := json.Marshal(payload)
-	log := models.Log{
-		UserID: uID,
-		Action: "visit-link",
-		Data:   datatypes.JSON(b),
+	destination := link.URL
+	if rule := services.MatchLinkRule(rules, visit); rule != nil {
+		destination = rule.URL
+		visit.RuleID = &rule.ID
+	} else if variant := services.PickLinkVariant(variants, link.ID, uID); variant != nil {
+		destination = variant.URL
+		visit.VariantID = &variant.ID
 	}
-	/** Save log to database */
-	if err := database.DB.Create(&log).Error; err != nil {
+	/** Forward the path and query to the destination when the link allows it */
+	destination, err = destinationURL(c, link, destination, extraPath)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.Redirect(http.StatusFound, link.URL)
+	/** Visits are written in the background, so the click limit is enforced by an atomic counter on the link instead */
+	if link.MaxClicks != nil {
+		claimed, err := claimLinkClick(link.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !claimed {
+			respondLinkExpired(c)
+			return
+		}
+	}
+	/** Queue the visit, it is written in the background so the redirect never waits on it */
+	recordVisit(services.VisitEvent{
+		Visit:      visit,
+		ShortToken: shortToken,
+	})
+	c.Redirect(redirectStatus(c, link), destination)
 }
 
 func GetLinkStats(c *gin.Context) {
-	shortToken := c.Param("shortToken")
-	link, err := getLinkByShortToken(shortToken)
+	link, _, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+
+	totalVisits, err := countVisits(link.ID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	var totalVisits int64
-	var uniqueVisitors int64
+	uniqueVisitors, err := countUniqueVisitors(link.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	err = database.DB.Model(&models.Log{}).
-		Where("action = ?", "visit-link").
-		Where("data->>'shortToken' = ?", shortToken).
-		Count(&totalVisits).Error
+	breakdowns, err := linkBreakdowns(link.ID, breakdownLimit(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	uniqueVisitors, err = countUniqueVisitors(shortToken)
+	variants, err := queryVariantStats(link.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -195,14 +556,193 @@ func GetLinkStats(c *gin.Context) {
 		"link":           link,
 		"totalVisits":    totalVisits,
 		"uniqueVisitors": uniqueVisitors,
+		"breakdowns":     breakdowns,
+		"variants":       variants,
 	})
 }
 
 func DeleteLink(c *gin.Context) {
 	shortToken := c.Param("shortToken")
+	if _, _, ok := authorizeLink(c); !ok {
+		return
+	}
 	if err := deleteLink(shortToken); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Link deleted successfully"})
 }
+
+func ActivateLink(c *gin.Context) {
+	updateLinkActive(c, true)
+}
+
+func DeactivateLink(c *gin.Context) {
+	updateLinkActive(c, false)
+}
+
+/** updateLinkActive toggles the active flag of a link the authenticated user may manage */
+func updateLinkActive(c *gin.Context, active bool) {
+	shortToken := c.Param("shortToken")
+	_, u, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+	if err := setLinkActive(shortToken, active); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	/** Create link state log */
+	action := "deactivate-link"
+	if active {
+		action = "activate-link"
+	}
+	if !saveLog(c, u.ID, action, map[string]interface{}{
+		"shortToken": shortToken,
+		"active":     active,
+	}) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shortToken": shortToken, "active": active})
+}
+
+func UpdateLink(c *gin.Context) {
+	var req dto.UpdateLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	/** The URL can be left out when only the redirect options change */
+	optionsOnly := req.RedirectType != nil || req.ForwardQuery != nil || req.ForwardPath != nil
+	if req.URL != "" || !optionsOnly {
+		/** Validate URL */
+		if err := Validator.ValidateUrlDirect(req.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing URL"})
+			return
+		}
+		/** Validate URL Format */
+		if err := Validator.ValidateUrlFormatDirect(req.URL); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid URL format"})
+			return
+		}
+	}
+	/** Validate redirect type, zero goes back to the server default */
+	if req.RedirectType != nil && *req.RedirectType != 0 && !models.IsValidRedirectType(*req.RedirectType) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Redirect type must be one of 301, 302, 307 or 308"})
+		return
+	}
+	shortToken := c.Param("shortToken")
+	link, u, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+	if req.URL == "" {
+		req.URL = link.URL
+	}
+	options := *link
+	if req.RedirectType != nil {
+		options.RedirectType = *req.RedirectType
+	}
+	if req.ForwardQuery != nil {
+		options.ForwardQuery = *req.ForwardQuery
+	}
+	if req.ForwardPath != nil {
+		options.ForwardPath = *req.ForwardPath
+	}
+	optionsChanged := options.RedirectType != link.RedirectType || options.ForwardQuery != link.ForwardQuery || options.ForwardPath != link.ForwardPath
+	if link.URL == req.URL && !optionsChanged {
+		c.JSON(http.StatusOK, gin.H{
+			"shortToken":    shortToken,
+			"url":           link.URL,
+			"redirect_type": link.RedirectType,
+			"forward_query": link.ForwardQuery,
+			"forward_path":  link.ForwardPath,
+		})
+		return
+	}
+	previousURL := link.URL
+	previousRedirectType := link.RedirectType
+	if link.URL != req.URL {
+		if err := updateLinkURL(link, req.URL, u.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		/** The cached preview belongs to the previous destination, replace or clear it */
+		link.URL = req.URL
+		if err := fetchOpenGraph(link); err != nil {
+			link.OGTitle, link.OGDescription, link.OGImage, link.OGSiteName, link.OGFetchedAt = "", "", "", "", nil
+		}
+		if err := updateLinkOpenGraph(link); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if optionsChanged {
+		link.RedirectType, link.ForwardQuery, link.ForwardPath = options.RedirectType, options.ForwardQuery, options.ForwardPath
+		if err := updateLinkRedirectOptions(link); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	/** Create update link log */
+	if !saveLog(c, u.ID, "update-link", map[string]interface{}{
+		"shortToken":         shortToken,
+		"before":             previousURL,
+		"after":              req.URL,
+		"redirectTypeBefore": previousRedirectType,
+		"redirectTypeAfter":  link.RedirectType,
+		"forwardQuery":       link.ForwardQuery,
+		"forwardPath":        link.ForwardPath,
+	}) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shortToken":    shortToken,
+		"url":           req.URL,
+		"redirect_type": link.RedirectType,
+		"forward_query": link.ForwardQuery,
+		"forward_path":  link.ForwardPath,
+	})
+}
+
+func RefreshLinkOpenGraph(c *gin.Context) {
+	link, u, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+	if err := fetchOpenGraph(link); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch OpenGraph data", "details": err.Error()})
+		return
+	}
+	if err := updateLinkOpenGraph(link); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	/** Create refresh log */
+	if !saveLog(c, u.ID, "refresh-open-graph", map[string]interface{}{
+		"shortToken": link.ShortToken,
+		"og_title":   link.OGTitle,
+	}) {
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+func GetLinkHistory(c *gin.Context) {
+	link, _, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+	histories, err := getLinkHistory(link.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, histories)
+}
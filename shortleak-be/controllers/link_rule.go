@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"net/http"
+	"shortleak/dto"
+	"shortleak/models"
+	"shortleak/services"
+	"shortleak/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	getLinkRules   = services.GetLinkRules
+	getLinkRule    = services.GetLinkRule
+	createLinkRule = services.CreateLinkRule
+	updateLinkRule = services.UpdateLinkRule
+	deleteLinkRule = services.DeleteLinkRule
+)
+
+/** bindLinkRule reads and validates a rule request, it responds itself when the request is invalid */
+func bindLinkRule(c *gin.Context) (dto.LinkRuleRequest, bool) {
+	var req dto.LinkRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	/** Validate URL */
+	if err := Validator.ValidateUrlDirect(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing URL"})
+		return req, false
+	}
+	/** Validate URL Format */
+	if err := Validator.ValidateUrlFormatDirect(req.URL); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid URL format"})
+		return req, false
+	}
+	/** A rule without conditions would hide the link URL from everyone */
+	if req.OS == "" && req.Device == "" && req.Language == "" && req.Country == "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Rule needs at least one condition"})
+		return req, false
+	}
+	switch req.Device {
+	case "", "desktop", "mobile", "tablet", "bot":
+	default:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Device must be one of desktop, mobile, tablet or bot"})
+		return req, false
+	}
+	/** A rule only matches what the User-Agent parser reports, so the OS must be one of its names */
+	if req.OS != "" {
+		osName, ok := utils.CanonicalOS(req.OS)
+		if !ok {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "OS must be one of iOS, Android, Windows, ChromeOS, macOS, Linux or Other"})
+			return req, false
+		}
+		req.OS = osName
+	}
+	country, ok := normalizeCountries(req.Country)
+	if !ok {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Country must be ISO 3166-1 alpha-2 codes separated by commas"})
+		return req, false
+	}
+	req.Country = country
+	language, ok := normalizeLanguage(req.Language)
+	if !ok {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Language must be an ISO 639 language code like en or id"})
+		return req, false
+	}
+	req.Language = language
+	return req, true
+}
+
+/** normalizeLanguage keeps the lower cased primary subtag, visits only store that part of Accept-Language */
+func normalizeLanguage(value string) (string, bool) {
+	if value == "" {
+		return "", true
+	}
+	language := utils.PrimaryLanguage(value)
+	if len(language) < 2 || len(language) > 3 {
+		return "", false
+	}
+	for _, r := range language {
+		if r < 'a' || r > 'z' {
+			return "", false
+		}
+	}
+	return language, true
+}
+
+/** normalizeCountries upper cases a comma separated list of country codes, ok is false for an invalid code */
+func normalizeCountries(value string) (string, bool) {
+	if value == "" {
+		return "", true
+	}
+	codes := strings.Split(value, ",")
+	for i, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+			return "", false
+		}
+		codes[i] = code
+	}
+	return strings.Join(codes, ","), true
+}
+
+/** findLinkRule loads the rule from the path, it must belong to link */
+func findLinkRule(c *gin.Context, link *models.Link) (*models.LinkRule, bool) {
+	ruleID, err := uuid.Parse(c.Param("ruleId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return nil, false
+	}
+	rule, err := getLinkRule(link.ID, ruleID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return nil, false
+	}
+	return rule, true
+}
+
+func GetLinkRules(c *gin.Context) {
+	link, _, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+	rules, err := getLinkRules(link.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+func CreateLinkRule(c *gin.Context) {
+	req, ok := bindLinkRule(c)
+	if !ok {
+		return
+	}
+	link, u, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+	rule := models.LinkRule{
+		LinkID:   link.ID,
+		Priority: req.Priority,
+		OS:       req.OS,
+		Device:   req.Device,
+		Language: req.Language,
+		Country:  req.Country,
+		URL:      req.URL,
+	}
+	if err := createLinkRule(&rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !saveLog(c, u.ID, "create-link-rule", gin.H{"shortToken": link.ShortToken, "rule": rule}) {
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+func UpdateLinkRule(c *gin.Context) {
+	req, ok := bindLinkRule(c)
+	if !ok {
+		return
+	}
+	link, u, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+	rule, ok := findLinkRule(c, link)
+	if !ok {
+		return
+	}
+	rule.Priority = req.Priority
+	rule.OS = req.OS
+	rule.Device = req.Device
+	rule.Language = req.Language
+	rule.Country = req.Country
+	rule.URL = req.URL
+	if err := updateLinkRule(rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !saveLog(c, u.ID, "update-link-rule", gin.H{"shortToken": link.ShortToken, "rule": rule}) {
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func DeleteLinkRule(c *gin.Context) {
+	link, u, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+	rule, ok := findLinkRule(c, link)
+	if !ok {
+		return
+	}
+	if err := deleteLinkRule(rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !saveLog(c, u.ID, "delete-link-rule", gin.H{"shortToken": link.ShortToken, "rule": rule}) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted successfully"})
+}
@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shortleak/database"
+	"shortleak/models"
+	"shortleak/utils"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateLinkRuleWithoutCondition(t *testing.T) {
+	Validator = utils.DefaultValidator{}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("POST", "/links/abcde/rules", strings.NewReader(`{"url":"https://example.com"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	CreateLinkRule(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "at least one condition")
+}
+
+func TestCreateLinkRuleInvalidDevice(t *testing.T) {
+	Validator = utils.DefaultValidator{}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("POST", "/links/abcde/rules", strings.NewReader(`{"url":"https://example.com","device":"watch"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	CreateLinkRule(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestCreateLinkRuleInvalidOS(t *testing.T) {
+	Validator = utils.DefaultValidator{}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("POST", "/links/abcde/rules", strings.NewReader(`{"url":"https://example.com","os":"Symbian"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	CreateLinkRule(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "OS must be one of")
+}
+
+func TestCreateLinkRuleInvalidCountry(t *testing.T) {
+	Validator = utils.DefaultValidator{}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("POST", "/links/abcde/rules", strings.NewReader(`{"url":"https://example.com","country":"DE,Germany"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	CreateLinkRule(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "Country")
+}
+
+func TestNormalizeCountries(t *testing.T) {
+	countries, ok := normalizeCountries(" de, at ,CH")
+	assert.True(t, ok)
+	assert.Equal(t, "DE,AT,CH", countries)
+
+	_, ok = normalizeCountries("DE,,AT")
+	assert.False(t, ok)
+}
+
+func TestCreateLinkRuleInvalidLanguage(t *testing.T) {
+	Validator = utils.DefaultValidator{}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("POST", "/links/abcde/rules", strings.NewReader(`{"url":"https://example.com","language":"english"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	CreateLinkRule(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "Language")
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	// hanya subtag utama yang disimpan karena visit.Language juga begitu
+	language, ok := normalizeLanguage("en-US")
+	assert.True(t, ok)
+	assert.Equal(t, "en", language)
+
+	language, ok = normalizeLanguage(" FIL ")
+	assert.True(t, ok)
+	assert.Equal(t, "fil", language)
+
+	for _, value := range []string{"*", "e", "english", "e1"} {
+		_, ok = normalizeLanguage(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestRedirectLinkCountryRule(t *testing.T) {
+	link := &models.Link{URL: "https://example.com/promo", Active: true}
+	rule := models.LinkRule{ID: uuid.New(), Country: "ID", URL: "https://example.co.id/promo"}
+	mockLinkTargets(t, []models.LinkRule{rule}, nil)
+	origGeo := lookupGeo
+	lookupGeo = func(ip string) utils.GeoLocation { return utils.GeoLocation{Country: "US"} }
+	defer func() { lookupGeo = origGeo }()
+	origCfg := AppConfig
+	defer func() { AppConfig = origCfg }()
+
+	// tanpa header terpercaya, negara dari GeoIP
+	w, _ := serveRedirect(t, link, "/promo", "")
+	assert.Equal(t, "https://example.com/promo", w.Header().Get("Location"))
+
+	// header CF-IPCountry dipakai jika dikonfigurasi
+	AppConfig.TrustedCountryHeader = "CF-IPCountry"
+	mockLinkTargets(t, []models.LinkRule{rule}, nil)
+	events := mockRecordVisit(t)
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) { return link, nil }
+	defer func() { getLinkByShortToken = orig }()
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "promo"}}
+	c.Request, _ = http.NewRequest("GET", "/promo", nil)
+	c.Request.Header.Set("CF-IPCountry", "id")
+	c.Set("client_id", uuid.New().String())
+	RedirectLink(c)
+
+	assert.Equal(t, "https://example.co.id/promo", rec.Header().Get("Location"))
+	assert.Equal(t, "ID", (*events)[0].Visit.Country)
+}
+
+func TestTrustedCountryIgnoresUnknown(t *testing.T) {
+	origCfg := AppConfig
+	defer func() { AppConfig = origCfg }()
+	AppConfig.TrustedCountryHeader = "CF-IPCountry"
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.Request.Header.Set("CF-IPCountry", "XX")
+
+	assert.Equal(t, "", trustedCountry(c))
+}
+
+func TestCreateLinkRuleForbidden(t *testing.T) {
+	Validator = utils.DefaultValidator{}
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{URL: "https://example.com", ShortToken: token, UserID: uuid.New()}, nil
+	}
+	defer func() { getLinkByShortToken = orig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("POST", "/links/abcde/rules", strings.NewReader(`{"url":"https://apps.apple.com","os":"iOS"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", models.User{ID: uuid.New()})
+
+	CreateLinkRule(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestUpdateLinkRuleInvalidID(t *testing.T) {
+	Validator = utils.DefaultValidator{}
+	user := models.User{ID: uuid.New()}
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{URL: "https://example.com", ShortToken: token, UserID: user.ID}, nil
+	}
+	defer func() { getLinkByShortToken = orig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}, {Key: "ruleId", Value: "not-a-uuid"}}
+	c.Request, _ = http.NewRequest("PUT", "/links/abcde/rules/not-a-uuid", strings.NewReader(`{"url":"https://apps.apple.com","os":"iOS"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", user)
+
+	UpdateLinkRule(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLinkRuleLifecycle(t *testing.T) {
+	setupTestLinkDB(t)
+	user := createTestUser(t)
+	Validator = utils.DefaultValidator{}
+
+	link := models.Link{URL: "https://example.com", UserID: user.ID, ShortToken: "app01"}
+	database.DB.Create(&link)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", user)
+	})
+	r.GET("/links/:shortToken/rules", GetLinkRules)
+	r.POST("/links/:shortToken/rules", CreateLinkRule)
+	r.PUT("/links/:shortToken/rules/:ruleId", UpdateLinkRule)
+	r.DELETE("/links/:shortToken/rules/:ruleId", DeleteLinkRule)
+
+	// buat rule iOS
+	req, _ := http.NewRequest("POST", "/links/app01/rules", strings.NewReader(`{"url":"https://apps.apple.com/app/shortleak","os":"iOS"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var rule models.LinkRule
+	json.Unmarshal(w.Body.Bytes(), &rule)
+	assert.Equal(t, link.ID, rule.LinkID)
+
+	// ubah jadi rule android
+	req, _ = http.NewRequest("PUT", "/links/app01/rules/"+rule.ID.String(), strings.NewReader(`{"url":"https://play.google.com/store","os":"Android","priority":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/links/app01/rules", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var rules []models.LinkRule
+	json.Unmarshal(w.Body.Bytes(), &rules)
+	assert.Len(t, rules, 1)
+	assert.Equal(t, "Android", rules[0].OS)
+	assert.Equal(t, "https://play.google.com/store", rules[0].URL)
+
+	req, _ = http.NewRequest("DELETE", "/links/app01/rules/"+rule.ID.String(), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var count int64
+	database.DB.Model(&models.LinkRule{}).Where("link_id = ?", link.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	var logCount int64
+	database.DB.Model(&models.Log{}).Where("action IN ?", []string{"create-link-rule", "update-link-rule", "delete-link-rule"}).Count(&logCount)
+	assert.Equal(t, int64(3), logCount)
+}
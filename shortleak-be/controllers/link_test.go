@@ -4,21 +4,26 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"shortleak/database"
 	"shortleak/dto"
 	"shortleak/models"
+	"shortleak/services"
 	"shortleak/utils"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/dyatlov/go-opengraph/opengraph"
+	"github.com/dyatlov/go-opengraph/opengraph/types/image"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/datatypes"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -54,18 +59,25 @@ func setupTestLinkDB(t *testing.T) {
 	}
 
 	// bersihkan tabel agar fresh
-	err = db.Migrator().DropTable(&models.User{}, &models.Log{}, &models.Link{})
+	err = db.Migrator().DropTable(&models.User{}, &models.Log{}, &models.Link{}, &models.LinkHistory{}, &models.Visit{}, &models.VisitDailyRollup{}, &models.VisitDailyDimension{}, &models.LinkRule{}, &models.LinkVariant{})
 	if err != nil {
 		t.Fatalf("failed to drop tables: %v", err)
 	}
 
 	// migrasi ulang tabel
-	err = db.AutoMigrate(&models.User{}, &models.Log{}, &models.Link{})
+	err = db.AutoMigrate(&models.User{}, &models.Log{}, &models.Link{}, &models.LinkHistory{}, &models.Visit{}, &models.VisitDailyRollup{}, &models.VisitDailyDimension{}, &models.LinkRule{}, &models.LinkVariant{})
 	if err != nil {
 		t.Fatalf("failed to migrate test DB: %v", err)
 	}
 
 	database.DB = db
+
+	// jangan scraping OpenGraph ke internet saat test
+	origOG := getOpenGraphData
+	getOpenGraphData = func(url string) (opengraph.OpenGraph, error) {
+		return opengraph.OpenGraph{}, errors.New("offline")
+	}
+	t.Cleanup(func() { getOpenGraphData = origOG })
 }
 
 func createTestUser(t *testing.T) models.User {
@@ -242,7 +254,10 @@ func TestDeleteLinkSuccess(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
-	r.DELETE("/links/:shortToken", DeleteLink)
+	r.DELETE("/links/:shortToken", func(c *gin.Context) {
+		c.Set("user", user)
+		DeleteLink(c)
+	})
 
 	req, _ := http.NewRequest("DELETE", "/links/del12", nil)
 	w := httptest.NewRecorder()
@@ -481,132 +496,275 @@ func TestRedirectLinkNotFound(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "Link not found")
 }
 
-func TestRedirectLinkOGError(t *testing.T) {
-	setupTestLinkDB(t)
+func mockRecordVisit(t *testing.T) *[]services.VisitEvent {
+	events := []services.VisitEvent{}
+	orig := recordVisit
+	recordVisit = func(event services.VisitEvent) bool {
+		events = append(events, event)
+		return true
+	}
+	t.Cleanup(func() { recordVisit = orig })
+	return &events
+}
+
+/** mockLinkTargets mengganti rule dan variant link yang dibaca RedirectLink */
+func mockLinkTargets(t *testing.T, rules []models.LinkRule, variants []models.LinkVariant) {
+	origRules := getLinkRules
+	getLinkRules = func(linkID uuid.UUID) ([]models.LinkRule, error) {
+		return rules, nil
+	}
+	origVariants := getLinkVariants
+	getLinkVariants = func(linkID uuid.UUID) ([]models.LinkVariant, error) {
+		return variants, nil
+	}
+	t.Cleanup(func() {
+		getLinkRules = origRules
+		getLinkVariants = origVariants
+	})
+}
 
-	// mock link service return success
+func TestRedirectLinkWithoutClientID(t *testing.T) {
+	events := mockRecordVisit(t)
+	mockLinkTargets(t, nil, nil)
 	orig := getLinkByShortToken
 	getLinkByShortToken = func(token string) (*models.Link, error) {
-		return &models.Link{
-			URL: "http://example.com",
-		}, nil
+		return &models.Link{URL: "http://example.com", Active: true}, nil
 	}
 	defer func() { getLinkByShortToken = orig }()
 
-	// override OG parser -> return error
-	origOG := getOpenGraphData
-	getOpenGraphData = func(url string) (opengraph.OpenGraph, error) {
-		return opengraph.OpenGraph{}, errors.New("og error")
-	}
-	defer func() { getOpenGraphData = origOG }()
-
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("GET", "/links/abcde", nil)
+	// tanpa client_id, redirect tetap jalan dengan client baru
 
 	RedirectLink(c)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Contains(t, w.Body.String(), "og error")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Len(t, *events, 1)
+	assert.NotEqual(t, uuid.Nil, (*events)[0].Visit.ClientID)
 }
 
-func TestRedirectLinkInvalidClientID(t *testing.T) {
-	setupTestLinkDB(t)
+func TestRedirectLinkDoesNotWaitForDatabase(t *testing.T) {
+	// DB tidak tersedia, redirect tetap jalan karena visit ditulis di background
+	origDB := database.DB
+	database.DB = nil
+	defer func() { database.DB = origDB }()
+	events := mockRecordVisit(t)
+	mockLinkTargets(t, nil, nil)
 
-	// mock link + OG data ok
+	orig := getLinkByShortToken
 	getLinkByShortToken = func(token string) (*models.Link, error) {
-		return &models.Link{URL: "http://example.com"}, nil
-	}
-	getOpenGraphData = func(url string) (opengraph.OpenGraph, error) {
-		return opengraph.OpenGraph{
-			Title:       "Example Title",
-			Description: "Example Description",
-			URL:         url,
-		}, nil
+		return &models.Link{URL: "http://example.com", Active: true}, nil
 	}
+	defer func() { getLinkByShortToken = orig }()
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
 	c.Request, _ = http.NewRequest("GET", "/links/abcde", nil)
-	// invalid uuid string
-	c.Request.AddCookie(&http.Cookie{Name: "client_id", Value: "not-a-uuid"})
+	c.Set("client_id", uuid.New().String())
 
 	RedirectLink(c)
 
-	assert.Equal(t, http.StatusUnauthorized, w.Code)
-	assert.Contains(t, w.Body.String(), "Invalid client ID")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Len(t, *events, 1)
 }
 
-func setupTestLinkDBNoLogs(t *testing.T) {
-	dsn := "host=localhost user=postgres password=12345 dbname=shortleak-test port=5432 sslmode=disable TimeZone=Asia/Jakarta"
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("failed to connect test DB: %v", err)
+func TestRedirectLinkSuccess(t *testing.T) {
+	events := mockRecordVisit(t)
+	mockLinkTargets(t, nil, nil)
+	linkID := uuid.New()
+	clientID := uuid.New()
+
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{ID: linkID, URL: "http://example.com", Active: true}, nil
 	}
+	defer func() { getLinkByShortToken = orig }()
 
-	// drop semua tabel
-	_ = db.Migrator().DropTable(&models.User{}, &models.Link{}, &models.Log{})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("GET", "/links/abcde", nil)
+	c.Request.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Firefox/118.0")
+	c.Set("client_id", clientID.String())
 
-	// migrasi hanya User & Link, jangan Log
-	if err := db.AutoMigrate(&models.User{}, &models.Link{}); err != nil {
-		t.Fatalf("failed to migrate: %v", err)
-	}
+	RedirectLink(c)
 
-	database.DB = db
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "http://example.com", w.Header().Get("Location"))
+	assert.Len(t, *events, 1)
+	event := (*events)[0]
+	assert.Equal(t, "abcde", event.ShortToken)
+	assert.Equal(t, linkID, event.Visit.LinkID)
+	assert.Equal(t, clientID, event.Visit.ClientID)
+	assert.Equal(t, "Firefox", event.Visit.Browser)
+	assert.NotEqual(t, uuid.Nil, event.Visit.ID)
 }
 
-func TestRedirectLinkLogSaveError(t *testing.T) {
-	// pakai setup tanpa logs
-	setupTestLinkDBNoLogs(t)
-
+func TestRedirectLinkUsesLinkRedirectType(t *testing.T) {
+	mockRecordVisit(t)
+	mockLinkTargets(t, nil, nil)
+	orig := getLinkByShortToken
 	getLinkByShortToken = func(token string) (*models.Link, error) {
-		return &models.Link{URL: "http://example.com"}, nil
-	}
-	getOpenGraphData = func(url string) (opengraph.OpenGraph, error) {
-		return opengraph.OpenGraph{
-			Title:       "Example Title",
-			Description: "Example Description",
-			URL:         url,
-		}, nil
+		return &models.Link{URL: "http://example.com", Active: true, RedirectType: http.StatusPermanentRedirect}, nil
 	}
+	defer func() { getLinkByShortToken = orig }()
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
-	c.Request, _ = http.NewRequest("GET", "/links/abcde", nil)
-	c.Request.AddCookie(&http.Cookie{Name: "client_id", Value: uuid.New().String()})
+	c.Request, _ = http.NewRequest("POST", "/abcde", nil)
+	c.Set("client_id", uuid.New().String())
 
 	RedirectLink(c)
+	// redirect POST tanpa body, header ditulis engine gin setelah handler
+	c.Writer.WriteHeaderNow()
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Contains(t, w.Body.String(), "does not exist")
+	assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+	assert.Equal(t, "http://example.com", w.Header().Get("Location"))
 }
 
-func TestRedirectLinkSuccess(t *testing.T) {
-	setupTestLinkDB(t)
-
+func TestRedirectLinkUsesDefaultRedirectType(t *testing.T) {
+	mockRecordVisit(t)
+	mockLinkTargets(t, nil, nil)
+	orig := getLinkByShortToken
 	getLinkByShortToken = func(token string) (*models.Link, error) {
-		return &models.Link{URL: "http://example.com"}, nil
+		return &models.Link{URL: "http://example.com", Active: true}, nil
 	}
-	getOpenGraphData = func(url string) (opengraph.OpenGraph, error) {
-		return opengraph.OpenGraph{
-			Title:       "Example Title",
-			Description: "Example Description",
-			URL:         url,
-		}, nil
+	defer func() { getLinkByShortToken = orig }()
+	origCfg := AppConfig
+	defer func() { AppConfig = origCfg }()
+
+	codes := []int{}
+	for _, defaultType := range []int{http.StatusMovedPermanently, 0, 418} {
+		AppConfig.DefaultRedirectType = defaultType
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+		c.Request, _ = http.NewRequest("GET", "/abcde", nil)
+		c.Set("client_id", uuid.New().String())
+
+		RedirectLink(c)
+		codes = append(codes, w.Code)
+	}
+
+	// default tidak valid kembali ke 302
+	assert.Equal(t, []int{http.StatusMovedPermanently, http.StatusFound, http.StatusFound}, codes)
+}
+
+/** serveRedirect menjalankan RedirectLink lewat router dengan route path passthrough */
+func serveRedirect(t *testing.T, link *models.Link, target, userAgent string) (*httptest.ResponseRecorder, *[]services.VisitEvent) {
+	events := mockRecordVisit(t)
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return link, nil
 	}
+	t.Cleanup(func() { getLinkByShortToken = orig })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:shortToken", RedirectLink)
+	r.GET("/:shortToken/*path", RedirectLink)
 
+	req, _ := http.NewRequest("GET", target, nil)
+	req.Header.Set("User-Agent", userAgent)
 	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
-	c.Request, _ = http.NewRequest("GET", "/links/abcde", nil)
-	c.Request.AddCookie(&http.Cookie{Name: "client_id", Value: uuid.New().String()})
+	r.ServeHTTP(w, req)
+	return w, events
+}
 
-	RedirectLink(c)
+func TestRedirectLinkForwardsQueryAndPath(t *testing.T) {
+	mockLinkTargets(t, nil, nil)
+	link := &models.Link{URL: "https://example.com/docs?ref=partner", Active: true, ForwardQuery: true, ForwardPath: true}
+
+	w, _ := serveRedirect(t, link, "/abcde/guide/intro?utm_source=mail&ref=spam", "")
 
 	assert.Equal(t, http.StatusFound, w.Code)
-	assert.Equal(t, "http://example.com", w.Header().Get("Location"))
+	assert.Equal(t, "https://example.com/docs/guide/intro?ref=partner&utm_source=mail", w.Header().Get("Location"))
+}
+
+func TestRedirectLinkIgnoresQueryByDefault(t *testing.T) {
+	mockLinkTargets(t, nil, nil)
+	link := &models.Link{URL: "https://example.com/docs", Active: true}
+
+	w, _ := serveRedirect(t, link, "/abcde?utm_source=mail", "")
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com/docs", w.Header().Get("Location"))
+}
+
+func TestRedirectLinkPathNotForwarded(t *testing.T) {
+	mockLinkTargets(t, nil, nil)
+	link := &models.Link{URL: "https://example.com/docs", Active: true, ForwardQuery: true}
+
+	w, _ := serveRedirect(t, link, "/abcde/extra", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// trailing slash tetap dianggap link yang sama
+	w, _ = serveRedirect(t, link, "/abcde/", "")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com/docs", w.Header().Get("Location"))
+}
+
+func TestRedirectLinkTargetingRule(t *testing.T) {
+	link := &models.Link{URL: "https://example.com", Active: true, ForwardQuery: true}
+	rule := models.LinkRule{ID: uuid.New(), OS: "iOS", URL: "https://apps.apple.com/app/shortleak"}
+	iphone := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148 Safari/604.1"
+	mockLinkTargets(t, []models.LinkRule{rule}, nil)
+
+	w, events := serveRedirect(t, link, "/abcde?utm_source=mail", iphone)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://apps.apple.com/app/shortleak?utm_source=mail", w.Header().Get("Location"))
+	assert.Len(t, *events, 1)
+	assert.Equal(t, rule.ID, *(*events)[0].Visit.RuleID)
+
+	// desktop tidak cocok, pakai URL link sebagai fallback
+	w, events = serveRedirect(t, link, "/abcde", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0")
+
+	assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+	assert.Nil(t, (*events)[0].Visit.RuleID)
+}
+
+func TestRedirectLinkWeightedVariant(t *testing.T) {
+	link := &models.Link{ID: uuid.New(), URL: "https://example.com", Active: true}
+	variants := []models.LinkVariant{
+		{ID: uuid.New(), URL: "https://example.com/a", Weight: 1},
+		{ID: uuid.New(), URL: "https://example.com/b", Weight: 1},
+	}
+	rule := models.LinkRule{ID: uuid.New(), Device: "bot", URL: "https://example.com/bot"}
+	mockLinkTargets(t, []models.LinkRule{rule}, variants)
+	events := mockRecordVisit(t)
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return link, nil
+	}
+	defer func() { getLinkByShortToken = orig }()
+
+	redirect := func(clientID uuid.UUID, userAgent string) string {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "shortToken", Value: "split"}}
+		c.Request, _ = http.NewRequest("GET", "/split", nil)
+		c.Request.Header.Set("User-Agent", userAgent)
+		c.Set("client_id", clientID.String())
+		RedirectLink(c)
+		return w.Header().Get("Location")
+	}
+
+	// client yang sama tetap di variant yang sama
+	clientID := uuid.New()
+	expected := services.PickLinkVariant(variants, link.ID, clientID)
+	assert.Equal(t, expected.URL, redirect(clientID, "Firefox/118.0"))
+	assert.Equal(t, expected.URL, redirect(clientID, "Firefox/118.0"))
+	assert.Equal(t, expected.ID, *(*events)[0].Visit.VariantID)
+
+	// rule targeting menang atas variant
+	assert.Equal(t, "https://example.com/bot", redirect(clientID, "Googlebot/2.1"))
+	assert.Nil(t, (*events)[2].Visit.VariantID)
+	assert.Equal(t, rule.ID, *(*events)[2].Visit.RuleID)
 }
 
 func TestGetLinkStatsNotFound(t *testing.T) {
@@ -622,6 +780,7 @@ func TestGetLinkStatsNotFound(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Set("user", models.User{})
 
 	GetLinkStats(c)
 
@@ -639,12 +798,13 @@ func TestGetLinkStatsDBErrorTotalVisits(t *testing.T) {
 	}
 	defer func() { getLinkByShortToken = orig }()
 
-	// pakai DB rusak → drop tabel logs biar query gagal
-	_ = database.DB.Migrator().DropTable(&models.Log{})
+	// pakai DB rusak → drop tabel visits biar query gagal
+	_ = database.DB.Migrator().DropTable(&models.Visit{})
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Set("user", models.User{})
 
 	GetLinkStats(c)
 
@@ -655,28 +815,18 @@ func TestGetLinkStatsDBErrorTotalVisits(t *testing.T) {
 func TestCountUniqueVisitorsSuccess(t *testing.T) {
 	// pakai DB test
 	setupTestLinkDB(t)
-	database.DB.Exec("DELETE FROM logs")
-
-	// Insert 3 logs -> 2 user berbeda (jadi hasilnya harus 2)
-	userID1 := uuid.New()
-	userID2 := uuid.New()
-	database.DB.Create(&models.Log{
-		Action: "visit-link",
-		UserID: userID1,
-		Data:   datatypes.JSON([]byte(`{"shortToken":"abcde"}`)),
-	})
-	database.DB.Create(&models.Log{
-		Action: "visit-link",
-		UserID: userID2,
-		Data:   datatypes.JSON([]byte(`{"shortToken":"abcde"}`)),
-	})
-	database.DB.Create(&models.Log{
-		Action: "visit-link",
-		UserID: userID1,
-		Data:   datatypes.JSON([]byte(`{"shortToken":"abcde"}`)),
-	})
+	user := createTestUser(t)
+	link := models.Link{UserID: user.ID, URL: "http://example.com", ShortToken: "abcde", Active: true}
+	database.DB.Create(&link)
 
-	uniqueVisitors, err := countUniqueVisitors("abcde")
+	// Insert 3 visits -> 2 client berbeda (jadi hasilnya harus 2)
+	clientID1 := uuid.New()
+	clientID2 := uuid.New()
+	database.DB.Create(&models.Visit{LinkID: link.ID, ClientID: clientID1})
+	database.DB.Create(&models.Visit{LinkID: link.ID, ClientID: clientID2})
+	database.DB.Create(&models.Visit{LinkID: link.ID, ClientID: clientID1})
+
+	uniqueVisitors, err := countUniqueVisitors(link.ID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(2), uniqueVisitors)
@@ -686,12 +836,12 @@ func TestCountUniqueVisitorsError(t *testing.T) {
 	// Simpan asli
 	origCount := countUniqueVisitors
 	// Override untuk simulate error
-	countUniqueVisitors = func(shortToken string) (int64, error) {
+	countUniqueVisitors = func(linkID uuid.UUID) (int64, error) {
 		return 0, errors.New("mock count unique visitors error")
 	}
 	defer func() { countUniqueVisitors = origCount }()
 
-	uniqueVisitors, err := countUniqueVisitors("abcde")
+	uniqueVisitors, err := countUniqueVisitors(uuid.New())
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "mock count unique visitors error")
@@ -713,7 +863,7 @@ func TestGetLinkStatsDBErrorUniqueVisitors(t *testing.T) {
 
 	// Mock countUniqueVisitors biar return error
 	origCount := countUniqueVisitors
-	countUniqueVisitors = func(shortToken string) (int64, error) {
+	countUniqueVisitors = func(linkID uuid.UUID) (int64, error) {
 		return 0, errors.New("mock unique visitors error")
 	}
 	defer func() { countUniqueVisitors = origCount }()
@@ -721,6 +871,7 @@ func TestGetLinkStatsDBErrorUniqueVisitors(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Set("user", models.User{})
 
 	GetLinkStats(c)
 
@@ -730,29 +881,34 @@ func TestGetLinkStatsDBErrorUniqueVisitors(t *testing.T) {
 
 func TestGetLinkStatsSuccess(t *testing.T) {
 	setupTestLinkDB(t)
+	user := createTestUser(t)
+	link := models.Link{UserID: user.ID, URL: "http://example.com", ShortToken: "abcde", Active: true}
+	database.DB.Create(&link)
 
 	// link ditemukan
 	orig := getLinkByShortToken
 	getLinkByShortToken = func(token string) (*models.Link, error) {
-		return &models.Link{ShortToken: token, URL: "http://example.com"}, nil
+		return &link, nil
 	}
 	defer func() { getLinkByShortToken = orig }()
 
-	// buat dummy logs
-	user1 := uuid.New()
-	user2 := uuid.New()
-	logs := []models.Log{
-		{UserID: user1, Action: "visit-link", Data: datatypes.JSON([]byte(`{"shortToken":"abcde"}`))},
-		{UserID: user1, Action: "visit-link", Data: datatypes.JSON([]byte(`{"shortToken":"abcde"}`))},
-		{UserID: user2, Action: "visit-link", Data: datatypes.JSON([]byte(`{"shortToken":"abcde"}`))},
+	// buat dummy visits
+	client1 := uuid.New()
+	client2 := uuid.New()
+	visits := []models.Visit{
+		{LinkID: link.ID, ClientID: client1, Browser: "Firefox"},
+		{LinkID: link.ID, ClientID: client1, Browser: "Firefox"},
+		{LinkID: link.ID, ClientID: client2, Browser: "Chrome"},
 	}
-	for _, l := range logs {
-		_ = database.DB.Create(&l).Error
+	for _, v := range visits {
+		_ = database.DB.Create(&v).Error
 	}
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("GET", "/stats/abcde", nil)
+	c.Set("user", user)
 
 	GetLinkStats(c)
 
@@ -761,6 +917,7 @@ func TestGetLinkStatsSuccess(t *testing.T) {
 	assert.Contains(t, body, "http://example.com")
 	assert.Contains(t, body, `"totalVisits":3`)
 	assert.Contains(t, body, `"uniqueVisitors":2`)
+	assert.Contains(t, body, `"browsers":[{"value":"Firefox","visits":2},{"value":"Chrome","visits":1}]`)
 }
 
 func TestDeleteLinkError(t *testing.T) {
@@ -772,6 +929,13 @@ func TestDeleteLinkError(t *testing.T) {
 	}
 	defer func() { deleteLink = origDeleteLink }()
 
+	owner := models.User{ID: uuid.New()}
+	origLink := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{ShortToken: token, UserID: owner.ID}, nil
+	}
+	defer func() { getLinkByShortToken = origLink }()
+
 	// Setup router
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
@@ -780,6 +944,7 @@ func TestDeleteLinkError(t *testing.T) {
 	req, _ := http.NewRequest(http.MethodDelete, "/links/abcde", nil)
 	c.Request = req
 	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Set("user", owner)
 
 	// Call handler
 	DeleteLink(c)
@@ -788,3 +953,906 @@ func TestDeleteLinkError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), "delete failed")
 }
+
+func TestCreateLinkWithAliasSuccess(t *testing.T) {
+	setupTestLinkDB(t)
+	user := createTestUser(t)
+	Validator = utils.DefaultValidator{}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := dto.LinkRequest{URL: "http://launch.com", Alias: "launch-2026"}
+	b, _ := json.Marshal(body)
+	c.Request, _ = http.NewRequest("POST", "/links", bytes.NewBuffer(b))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", user)
+
+	CreateLink(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), "launch-2026")
+}
+
+func TestCreateLinkAliasInvalid(t *testing.T) {
+	cases := map[string]string{
+		"too short":     "ab",
+		"invalid chars": "hello world!",
+		"reserved":      "Stats",
+	}
+	for name, alias := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body := dto.LinkRequest{URL: "http://example.com", Alias: alias}
+			b, _ := json.Marshal(body)
+			c.Request, _ = http.NewRequest("POST", "/links", bytes.NewBuffer(b))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user", models.User{ID: uuid.New()})
+
+			Validator = MockValidator{}
+			CreateLink(c)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			assert.Contains(t, w.Body.String(), "Invalid alias")
+		})
+	}
+}
+
+func TestCreateLinkAliasTaken(t *testing.T) {
+	setupTestLinkDB(t)
+	user := createTestUser(t)
+	Validator = utils.DefaultValidator{}
+
+	// alias sudah dipakai link lain
+	existing := models.Link{URL: "http://other.com", UserID: user.ID, ShortToken: "promo"}
+	database.DB.Create(&existing)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := dto.LinkRequest{URL: "http://new.com", Alias: "promo"}
+	b, _ := json.Marshal(body)
+	c.Request, _ = http.NewRequest("POST", "/links", bytes.NewBuffer(b))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", user)
+
+	CreateLink(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Alias is already taken")
+}
+
+func TestCreateLinkAliasTakenConcurrently(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	gdb, _ := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	origDB := database.DB
+	database.DB = gdb
+	t.Cleanup(func() { database.DB = origDB })
+	Validator = utils.DefaultValidator{}
+	mockOpenGraph(t, opengraph.OpenGraph{}, errors.New("no preview"))
+
+	// alias masih kosong saat dicek
+	mock.ExpectQuery(`SELECT \* FROM "links"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// request lain menyimpan alias yang sama sebelum insert ini
+	orig := createLink
+	createLink = func(link *models.Link) error {
+		return services.ErrShortTokenTaken
+	}
+	defer func() { createLink = orig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := dto.LinkRequest{URL: "http://new.com", Alias: "promo"}
+	b, _ := json.Marshal(body)
+	c.Request, _ = http.NewRequest("POST", "/links", bytes.NewBuffer(b))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", models.User{ID: uuid.New()})
+
+	CreateLink(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Alias is already taken")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateLinkExpiresAtInPast(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	past := time.Now().Add(-time.Hour)
+	body := dto.LinkRequest{URL: "http://example.com", ExpiresAt: &past}
+	b, _ := json.Marshal(body)
+	c.Request, _ = http.NewRequest("POST", "/links", bytes.NewBuffer(b))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", models.User{ID: uuid.New()})
+
+	Validator = MockValidator{}
+	CreateLink(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "Expiration date must be in the future")
+}
+
+func TestRedirectLinkExpiredByDate(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{URL: "http://example.com", ShortToken: token, Active: true, ExpiresAt: &past}, nil
+	}
+	defer func() { getLinkByShortToken = orig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("GET", "/abcde", nil)
+
+	RedirectLink(c)
+
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Contains(t, w.Body.String(), "Link has expired")
+}
+
+func TestRedirectLinkExpiredByMaxClicks(t *testing.T) {
+	maxClicks := int64(2)
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{URL: "http://example.com", ShortToken: token, Active: true, MaxClicks: &maxClicks, Clicks: 2}, nil
+	}
+	defer func() { getLinkByShortToken = orig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("GET", "/abcde", nil)
+
+	RedirectLink(c)
+
+	assert.Equal(t, http.StatusGone, w.Code)
+}
+
+/** mockClaimLinkClick mengganti counter klik link, claimed menentukan apakah klik masih tersedia */
+func mockClaimLinkClick(t *testing.T, claimed bool) *int {
+	calls := 0
+	orig := claimLinkClick
+	claimLinkClick = func(linkID uuid.UUID) (bool, error) {
+		calls++
+		return claimed, nil
+	}
+	t.Cleanup(func() { claimLinkClick = orig })
+	return &calls
+}
+
+func TestRedirectLinkClaimsClick(t *testing.T) {
+	events := mockRecordVisit(t)
+	mockLinkTargets(t, nil, nil)
+	calls := mockClaimLinkClick(t, true)
+	maxClicks := int64(2)
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{URL: "http://example.com", Active: true, MaxClicks: &maxClicks, Clicks: 1}, nil
+	}
+	defer func() { getLinkByShortToken = orig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("GET", "/abcde", nil)
+	c.Set("client_id", uuid.NewString())
+
+	RedirectLink(c)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, 1, *calls)
+	assert.Len(t, *events, 1)
+}
+
+func TestRedirectLinkLastClickTakenConcurrently(t *testing.T) {
+	events := mockRecordVisit(t)
+	mockLinkTargets(t, nil, nil)
+	// klik terakhir sudah diambil request lain setelah link dibaca
+	mockClaimLinkClick(t, false)
+	maxClicks := int64(2)
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{URL: "http://example.com", Active: true, MaxClicks: &maxClicks, Clicks: 1}, nil
+	}
+	defer func() { getLinkByShortToken = orig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("GET", "/abcde", nil)
+	c.Set("client_id", uuid.NewString())
+
+	RedirectLink(c)
+
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Empty(t, *events)
+}
+
+func TestRedirectLinkExpiredFallbackURL(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{URL: "http://example.com", ShortToken: token, Active: true, ExpiresAt: &past}, nil
+	}
+	defer func() { getLinkByShortToken = orig }()
+
+	AppConfig.ExpiredLinkURL = "http://fallback.com/expired"
+	defer func() { AppConfig.ExpiredLinkURL = "" }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("GET", "/abcde", nil)
+
+	RedirectLink(c)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "http://fallback.com/expired", w.Header().Get("Location"))
+}
+
+func TestRedirectLinkInactive(t *testing.T) {
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{URL: "http://example.com", ShortToken: token, Active: false}, nil
+	}
+	defer func() { getLinkByShortToken = orig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("GET", "/abcde", nil)
+
+	RedirectLink(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Link is inactive")
+}
+
+func TestDeactivateLinkForbidden(t *testing.T) {
+	owner := uuid.New()
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{URL: "http://example.com", ShortToken: token, UserID: owner, Active: true}, nil
+	}
+	defer func() { getLinkByShortToken = orig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("PATCH", "/links/abcde/deactivate", nil)
+	// user lain, bukan pemilik link
+	c.Set("user", models.User{ID: uuid.New()})
+
+	DeactivateLink(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestDeactivateLinkSuccess(t *testing.T) {
+	setupTestLinkDB(t)
+	user := createTestUser(t)
+
+	link := models.Link{URL: "https://campaign.com", UserID: user.ID, ShortToken: "camp1"}
+	database.DB.Create(&link)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PATCH("/links/:shortToken/deactivate", func(c *gin.Context) {
+		c.Set("user", user)
+		DeactivateLink(c)
+	})
+
+	req, _ := http.NewRequest("PATCH", "/links/camp1/deactivate", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"active":false`)
+
+	var updated models.Link
+	database.DB.First(&updated, "short_token = ?", "camp1")
+	assert.False(t, updated.Active)
+
+	var logCount int64
+	database.DB.Model(&models.Log{}).Where("action = ?", "deactivate-link").Count(&logCount)
+	assert.Equal(t, int64(1), logCount)
+}
+
+func TestUpdateLinkForbidden(t *testing.T) {
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{URL: "http://example.com", ShortToken: token, UserID: uuid.New()}, nil
+	}
+	defer func() { getLinkByShortToken = orig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+
+	b, _ := json.Marshal(dto.UpdateLinkRequest{URL: "http://example.org"})
+	c.Request, _ = http.NewRequest("PUT", "/links/abcde", bytes.NewBuffer(b))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", models.User{ID: uuid.New()})
+
+	Validator = MockValidator{}
+	UpdateLink(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestUpdateLinkInvalidFormat(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+
+	b, _ := json.Marshal(dto.UpdateLinkRequest{URL: "invalid-url"})
+	c.Request, _ = http.NewRequest("PUT", "/links/abcde", bytes.NewBuffer(b))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	Validator = MockValidator{validateURLFormatErr: errors.New("invalid format")}
+	UpdateLink(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestUpdateLinkSuccess(t *testing.T) {
+	setupTestLinkDB(t)
+	user := createTestUser(t)
+	Validator = utils.DefaultValidator{}
+
+	link := models.Link{URL: "https://typo.exmaple.com", UserID: user.ID, ShortToken: "upd12"}
+	database.DB.Create(&link)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/links/:shortToken", func(c *gin.Context) {
+		c.Set("user", user)
+		UpdateLink(c)
+	})
+
+	b, _ := json.Marshal(dto.UpdateLinkRequest{URL: "https://typo.example.com"})
+	req, _ := http.NewRequest("PUT", "/links/upd12", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated models.Link
+	database.DB.First(&updated, "short_token = ?", "upd12")
+	assert.Equal(t, "https://typo.example.com", updated.URL)
+
+	// destinasi lama tersimpan di history
+	var histories []models.LinkHistory
+	database.DB.Where("link_id = ?", link.ID).Find(&histories)
+	assert.Len(t, histories, 1)
+	assert.Equal(t, "https://typo.exmaple.com", histories[0].URL)
+
+	var logCount int64
+	database.DB.Model(&models.Log{}).Where("action = ?", "update-link").Count(&logCount)
+	assert.Equal(t, int64(1), logCount)
+}
+
+func TestUpdateLinkRedirectOptionsOnly(t *testing.T) {
+	setupTestLinkDB(t)
+	user := createTestUser(t)
+	Validator = utils.DefaultValidator{}
+
+	link := models.Link{URL: "https://example.com", UserID: user.ID, ShortToken: "red01"}
+	database.DB.Create(&link)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PATCH("/links/:shortToken", func(c *gin.Context) {
+		c.Set("user", user)
+		UpdateLink(c)
+	})
+
+	req, _ := http.NewRequest("PATCH", "/links/red01", strings.NewReader(`{"redirect_type":301,"forward_path":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated models.Link
+	database.DB.First(&updated, "short_token = ?", "red01")
+	assert.Equal(t, http.StatusMovedPermanently, updated.RedirectType)
+	assert.Equal(t, "https://example.com", updated.URL)
+
+	assert.True(t, updated.ForwardPath)
+	assert.False(t, updated.ForwardQuery)
+
+	// URL tidak berubah, history tidak bertambah
+	var historyCount int64
+	database.DB.Model(&models.LinkHistory{}).Where("link_id = ?", link.ID).Count(&historyCount)
+	assert.Equal(t, int64(0), historyCount)
+}
+
+func TestUpdateLinkInvalidRedirectType(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("PATCH", "/links/abcde", strings.NewReader(`{"redirect_type":303}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	UpdateLink(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "Redirect type")
+}
+
+func TestCreateLinkWithRedirectType(t *testing.T) {
+	setupTestLinkDB(t)
+	user := createTestUser(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/links", func(c *gin.Context) {
+		c.Set("user", user)
+		CreateLink(c)
+	})
+
+	req, _ := http.NewRequest("POST", "/links", strings.NewReader(`{"url":"https://example.com/api","redirect_type":307}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var link models.Link
+	database.DB.First(&link, "user_id = ?", user.ID)
+	assert.Equal(t, http.StatusTemporaryRedirect, link.RedirectType)
+}
+
+func TestCreateLinkInvalidRedirectType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/links", func(c *gin.Context) {
+		c.Set("user", models.User{ID: uuid.New()})
+		CreateLink(c)
+	})
+	Validator = utils.DefaultValidator{}
+
+	req, _ := http.NewRequest("POST", "/links", strings.NewReader(`{"url":"https://example.com","redirect_type":303}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "Redirect type")
+}
+
+func TestCreateLinkSameURLOtherUser(t *testing.T) {
+	setupTestLinkDB(t)
+	Validator = utils.DefaultValidator{}
+
+	other := models.User{ID: uuid.New(), FullName: "Teammate", Email: "mate@example.com"}
+	database.DB.Create(&other)
+	existing := models.Link{URL: "http://shared.com", UserID: other.ID, ShortToken: "mate1"}
+	database.DB.Create(&existing)
+
+	user := createTestUser(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := dto.LinkRequest{URL: "http://shared.com"}
+	b, _ := json.Marshal(body)
+	c.Request, _ = http.NewRequest("POST", "/links", bytes.NewBuffer(b))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", user)
+
+	CreateLink(c)
+
+	// token milik user lain tidak boleh bocor
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "mate1")
+}
+
+func TestCreateLinkAllowDuplicate(t *testing.T) {
+	setupTestLinkDB(t)
+	Validator = utils.DefaultValidator{}
+	user := createTestUser(t)
+
+	existing := models.Link{URL: "http://dup.com", UserID: user.ID, ShortToken: "dup01"}
+	database.DB.Create(&existing)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := dto.LinkRequest{URL: "http://dup.com", AllowDuplicate: true}
+	b, _ := json.Marshal(body)
+	c.Request, _ = http.NewRequest("POST", "/links", bytes.NewBuffer(b))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", user)
+
+	CreateLink(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "dup01")
+
+	var count int64
+	database.DB.Model(&models.Link{}).Where("user_id = ? AND url = ?", user.ID, "http://dup.com").Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestCreateLinkWithPasswordSkipsDuplicate(t *testing.T) {
+	setupTestLinkDB(t)
+	Validator = utils.DefaultValidator{}
+	user := createTestUser(t)
+
+	existing := models.Link{URL: "http://dup.com", UserID: user.ID, ShortToken: "dup02"}
+	database.DB.Create(&existing)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := dto.LinkRequest{URL: "http://dup.com", Password: "s3cret"}
+	b, _ := json.Marshal(body)
+	c.Request, _ = http.NewRequest("POST", "/links", bytes.NewBuffer(b))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", user)
+
+	CreateLink(c)
+
+	// link lama tanpa password tidak boleh dikembalikan
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "dup02")
+}
+
+func TestHasLinkOptions(t *testing.T) {
+	maxClicks := int64(1)
+	expiresAt := time.Now().Add(time.Hour)
+	assert.False(t, hasLinkOptions(dto.LinkRequest{URL: "http://dup.com"}))
+	for _, req := range []dto.LinkRequest{
+		{Password: "s3cret"},
+		{ExpiresAt: &expiresAt},
+		{MaxClicks: &maxClicks},
+		{RedirectType: http.StatusMovedPermanently},
+		{ForwardQuery: true},
+		{ForwardPath: true},
+	} {
+		assert.True(t, hasLinkOptions(req))
+	}
+}
+
+func TestDeleteLinkForbidden(t *testing.T) {
+	origLink := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{ShortToken: token, UserID: uuid.New()}, nil
+	}
+	defer func() { getLinkByShortToken = origLink }()
+
+	called := false
+	origDeleteLink := deleteLink
+	deleteLink = func(shortToken string) error {
+		called = true
+		return nil
+	}
+	defer func() { deleteLink = origDeleteLink }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodDelete, "/links/abcde", nil)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Set("user", models.User{ID: uuid.New(), Role: models.RoleUser})
+
+	DeleteLink(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, called, "link must not be deleted by non-owner")
+}
+
+func TestDeleteLinkUnauthorized(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodDelete, "/links/abcde", nil)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+
+	DeleteLink(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestGetLinkStatsForbidden(t *testing.T) {
+	origLink := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{ShortToken: token, UserID: uuid.New()}, nil
+	}
+	defer func() { getLinkByShortToken = origLink }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Set("user", models.User{ID: uuid.New()})
+
+	GetLinkStats(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Forbidden")
+}
+
+func TestGetLinkStatsAdminAllowed(t *testing.T) {
+	origLink := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{ShortToken: token, UserID: uuid.New(), URL: "http://example.com"}, nil
+	}
+	defer func() { getLinkByShortToken = origLink }()
+
+	origCount := countVisits
+	countVisits = func(linkID uuid.UUID) (int64, error) { return 4, nil }
+	defer func() { countVisits = origCount }()
+
+	origUnique := countUniqueVisitors
+	countUniqueVisitors = func(linkID uuid.UUID) (int64, error) { return 3, nil }
+	defer func() { countUniqueVisitors = origUnique }()
+
+	origBreakdown := queryVisitBreakdown
+	queryVisitBreakdown = func(linkID uuid.UUID, column string, limit int) ([]breakdownItem, error) {
+		return []breakdownItem{}, nil
+	}
+	defer func() { queryVisitBreakdown = origBreakdown }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	// admin boleh melihat stats link user lain
+	c.Set("user", models.User{ID: uuid.New(), Role: models.RoleAdmin})
+
+	GetLinkStats(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"totalVisits":4`)
+	assert.Contains(t, w.Body.String(), `"uniqueVisitors":3`)
+}
+
+func mockProtectedLink(t *testing.T, password string) {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{URL: "http://internal.example.com", ShortToken: token, Active: true, Password: string(hashed), PasswordProtected: true, OGTitle: "Internal Dashboard"}, nil
+	}
+	t.Cleanup(func() { getLinkByShortToken = orig })
+}
+
+func TestRedirectLinkPasswordRequired(t *testing.T) {
+	mockProtectedLink(t, "s3cret")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "prot1"}}
+	c.Request, _ = http.NewRequest("GET", "/prot1", nil)
+
+	RedirectLink(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Password required")
+}
+
+/** mockLinkPasswordLimiters mengganti batas percobaan password per IP dan per link */
+func mockLinkPasswordLimiters(t *testing.T, perIP, perLink int) {
+	origIP, origLink := linkPasswordLimiter, linkPasswordTotalLimiter
+	linkPasswordLimiter = utils.NewAttemptLimiter(perIP, time.Minute)
+	linkPasswordTotalLimiter = utils.NewAttemptLimiter(perLink, time.Minute)
+	t.Cleanup(func() { linkPasswordLimiter, linkPasswordTotalLimiter = origIP, origLink })
+}
+
+/** wrongLinkPassword mengirim password salah dari remoteAddr seperti di server tanpa trusted proxy */
+func wrongLinkPassword(remoteAddr, forwardedFor string) int {
+	w := httptest.NewRecorder()
+	c, engine := gin.CreateTestContext(w)
+	engine.SetTrustedProxies(nil)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "prot2"}}
+	c.Request, _ = http.NewRequest("GET", "/prot2", nil)
+	c.Request.RemoteAddr = remoteAddr
+	c.Request.Header.Set("X-Forwarded-For", forwardedFor)
+	c.Request.Header.Set("X-Link-Password", "wrong")
+	c.Set("client_id", uuid.NewString())
+
+	RedirectLink(c)
+	return w.Code
+}
+
+func TestRedirectLinkPasswordRateLimited(t *testing.T) {
+	mockProtectedLink(t, "s3cret")
+	mockLinkPasswordLimiters(t, 2, 50)
+
+	// client_id dan X-Forwarded-For baru di setiap percobaan tidak melewati batas
+	codes := []int{}
+	for i := 0; i < 3; i++ {
+		codes = append(codes, wrongLinkPassword("203.0.113.7:1234", fmt.Sprintf("10.0.0.%d", i)))
+	}
+
+	// 2 kali salah, percobaan ketiga diblokir
+	assert.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, codes)
+
+	// IP lain tidak ikut terblokir
+	assert.Equal(t, http.StatusUnauthorized, wrongLinkPassword("198.51.100.9:1234", ""))
+}
+
+func TestRedirectLinkPasswordRateLimitedPerLink(t *testing.T) {
+	mockProtectedLink(t, "s3cret")
+	mockLinkPasswordLimiters(t, 5, 3)
+
+	// setiap percobaan dari IP berbeda, batas per link tetap berlaku
+	codes := []int{}
+	for i := 0; i < 4; i++ {
+		codes = append(codes, wrongLinkPassword(fmt.Sprintf("203.0.113.%d:1234", i), ""))
+	}
+
+	assert.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, codes)
+}
+
+func TestRedirectLinkPasswordFormSuccess(t *testing.T) {
+	setupTestLinkDB(t)
+	mockProtectedLink(t, "s3cret")
+	mockRecordVisit(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "prot3"}}
+	c.Request, _ = http.NewRequest("POST", "/prot3", strings.NewReader("password=s3cret"))
+	c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c.Set("client_id", uuid.New().String())
+
+	RedirectLink(c)
+	c.Writer.WriteHeaderNow()
+
+	// password dari form tidak boleh dikirim ulang ke tujuan, jadi 303
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "http://internal.example.com", w.Header().Get("Location"))
+}
+
+func TestGetLinkByShortTokenHidesProtectedURL(t *testing.T) {
+	mockProtectedLink(t, "s3cret")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "prot4"}}
+
+	GetLinkByShortToken(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "internal.example.com")
+	assert.NotContains(t, w.Body.String(), "Internal Dashboard")
+	assert.Contains(t, w.Body.String(), `"password_protected":true`)
+}
+
+func TestGetLinkByShortTokenHidesOwner(t *testing.T) {
+	maxClicks := int64(10)
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{
+			URL:        "https://example.com",
+			ShortToken: token,
+			Active:     true,
+			MaxClicks:  &maxClicks,
+			User:       models.User{ID: uuid.New(), FullName: "John Doe", Email: "john@example.com"},
+		}, nil
+	}
+	defer func() { getLinkByShortToken = orig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abc12"}}
+
+	GetLinkByShortToken(c)
+
+	// route publik, pemilik dan pengaturan link tidak ikut dikirim
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"url":"https://example.com"`)
+	assert.NotContains(t, w.Body.String(), "john@example.com")
+	assert.NotContains(t, w.Body.String(), "max_clicks")
+	assert.NotContains(t, w.Body.String(), "user")
+}
+
+func mockOpenGraph(t *testing.T, og opengraph.OpenGraph, err error) {
+	orig := getOpenGraphData
+	getOpenGraphData = func(url string) (opengraph.OpenGraph, error) {
+		return og, err
+	}
+	t.Cleanup(func() { getOpenGraphData = orig })
+}
+
+func TestFetchOpenGraph(t *testing.T) {
+	mockOpenGraph(t, opengraph.OpenGraph{
+		Title:       "Example Title",
+		Description: "Example Description",
+		SiteName:    "Example",
+		Images:      []*image.Image{{URL: "http://example.com/a.png", SecureURL: "https://example.com/a.png"}},
+	}, nil)
+
+	link := models.Link{URL: "http://example.com"}
+	err := fetchOpenGraph(&link)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Example Title", link.OGTitle)
+	assert.Equal(t, "Example Description", link.OGDescription)
+	assert.Equal(t, "Example", link.OGSiteName)
+	assert.Equal(t, "https://example.com/a.png", link.OGImage)
+	assert.NotNil(t, link.OGFetchedAt)
+}
+
+func TestCreateLinkStoresOpenGraph(t *testing.T) {
+	setupTestLinkDB(t)
+	user := createTestUser(t)
+	mockOpenGraph(t, opengraph.OpenGraph{Title: "Google", SiteName: "Google"}, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/links", func(c *gin.Context) {
+		c.Set("user", user)
+		CreateLink(c)
+	})
+
+	b, _ := json.Marshal(dto.LinkRequest{URL: "https://google.com", Alias: "goog1"})
+	req, _ := http.NewRequest("POST", "/links", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var link models.Link
+	database.DB.First(&link, "short_token = ?", "goog1")
+	assert.Equal(t, "Google", link.OGTitle)
+	assert.NotNil(t, link.OGFetchedAt)
+}
+
+func TestRefreshLinkOpenGraphForbidden(t *testing.T) {
+	mockOwnedLink(t, models.User{ID: uuid.New()})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Set("user", models.User{ID: uuid.New()})
+
+	RefreshLinkOpenGraph(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRefreshLinkOpenGraphFetchError(t *testing.T) {
+	owner := models.User{ID: uuid.New()}
+	mockOwnedLink(t, owner)
+	mockOpenGraph(t, opengraph.OpenGraph{}, errors.New("timeout"))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Set("user", owner)
+
+	RefreshLinkOpenGraph(c)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to fetch OpenGraph data")
+}
+
+func TestRefreshLinkOpenGraphSuccess(t *testing.T) {
+	setupTestLinkDB(t)
+	user := createTestUser(t)
+	link := models.Link{URL: "http://example.com", UserID: user.ID, ShortToken: "ogref", Active: true}
+	database.DB.Create(&link)
+	mockOpenGraph(t, opengraph.OpenGraph{Title: "Fresh Title"}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "ogref"}}
+	c.Set("user", user)
+
+	RefreshLinkOpenGraph(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"og_title":"Fresh Title"`)
+	var updated models.Link
+	database.DB.First(&updated, "short_token = ?", "ogref")
+	assert.Equal(t, "Fresh Title", updated.OGTitle)
+}
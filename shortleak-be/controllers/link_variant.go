@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"net/http"
+	"shortleak/dto"
+	"shortleak/models"
+	"shortleak/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	getLinkVariants   = services.GetLinkVariants
+	getLinkVariant    = services.GetLinkVariant
+	createLinkVariant = services.CreateLinkVariant
+	updateLinkVariant = services.UpdateLinkVariant
+	deleteLinkVariant = services.DeleteLinkVariant
+)
+
+/** bindLinkVariant reads and validates a variant request, it responds itself when the request is invalid */
+func bindLinkVariant(c *gin.Context) (dto.LinkVariantRequest, bool) {
+	var req dto.LinkVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	/** Validate URL */
+	if err := Validator.ValidateUrlDirect(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing URL"})
+		return req, false
+	}
+	/** Validate URL Format */
+	if err := Validator.ValidateUrlFormatDirect(req.URL); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid URL format"})
+		return req, false
+	}
+	if req.Weight < 1 || req.Weight > 1000 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Weight must be between 1 and 1000"})
+		return req, false
+	}
+	return req, true
+}
+
+/** findLinkVariant loads the variant from the path, it must belong to link */
+func findLinkVariant(c *gin.Context, link *models.Link) (*models.LinkVariant, bool) {
+	variantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return nil, false
+	}
+	variant, err := getLinkVariant(link.ID, variantID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+		return nil, false
+	}
+	return variant, true
+}
+
+func GetLinkVariants(c *gin.Context) {
+	link, _, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+	variants, err := getLinkVariants(link.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, variants)
+}
+
+func CreateLinkVariant(c *gin.Context) {
+	req, ok := bindLinkVariant(c)
+	if !ok {
+		return
+	}
+	link, u, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+	variant := models.LinkVariant{
+		LinkID: link.ID,
+		Name:   req.Name,
+		URL:    req.URL,
+		Weight: req.Weight,
+	}
+	if err := createLinkVariant(&variant); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !saveLog(c, u.ID, "create-link-variant", gin.H{"shortToken": link.ShortToken, "variant": variant}) {
+		return
+	}
+	c.JSON(http.StatusCreated, variant)
+}
+
+func UpdateLinkVariant(c *gin.Context) {
+	req, ok := bindLinkVariant(c)
+	if !ok {
+		return
+	}
+	link, u, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+	variant, ok := findLinkVariant(c, link)
+	if !ok {
+		return
+	}
+	variant.Name = req.Name
+	variant.URL = req.URL
+	variant.Weight = req.Weight
+	if err := updateLinkVariant(variant); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !saveLog(c, u.ID, "update-link-variant", gin.H{"shortToken": link.ShortToken, "variant": variant}) {
+		return
+	}
+	c.JSON(http.StatusOK, variant)
+}
+
+func DeleteLinkVariant(c *gin.Context) {
+	link, u, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+	variant, ok := findLinkVariant(c, link)
+	if !ok {
+		return
+	}
+	if err := deleteLinkVariant(variant); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !saveLog(c, u.ID, "delete-link-variant", gin.H{"shortToken": link.ShortToken, "variant": variant}) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Variant deleted successfully"})
+}
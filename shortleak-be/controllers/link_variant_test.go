@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shortleak/database"
+	"shortleak/models"
+	"shortleak/utils"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateLinkVariantInvalidWeight(t *testing.T) {
+	Validator = utils.DefaultValidator{}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("POST", "/links/abcde/variants", strings.NewReader(`{"url":"https://example.com/a","weight":0}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	CreateLinkVariant(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "Weight")
+}
+
+func TestLinkVariantLifecycle(t *testing.T) {
+	setupTestLinkDB(t)
+	user := createTestUser(t)
+	Validator = utils.DefaultValidator{}
+
+	link := models.Link{URL: "https://example.com", UserID: user.ID, ShortToken: "ab001"}
+	database.DB.Create(&link)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", user)
+	})
+	r.GET("/links/:shortToken/variants", GetLinkVariants)
+	r.POST("/links/:shortToken/variants", CreateLinkVariant)
+	r.PUT("/links/:shortToken/variants/:variantId", UpdateLinkVariant)
+	r.DELETE("/links/:shortToken/variants/:variantId", DeleteLinkVariant)
+
+	req, _ := http.NewRequest("POST", "/links/ab001/variants", strings.NewReader(`{"name":"A","url":"https://example.com/a","weight":70}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var variant models.LinkVariant
+	json.Unmarshal(w.Body.Bytes(), &variant)
+
+	req, _ = http.NewRequest("PUT", "/links/ab001/variants/"+variant.ID.String(), strings.NewReader(`{"name":"A","url":"https://example.com/a2","weight":50}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/links/ab001/variants", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var variants []models.LinkVariant
+	json.Unmarshal(w.Body.Bytes(), &variants)
+	assert.Len(t, variants, 1)
+	assert.Equal(t, 50, variants[0].Weight)
+	assert.Equal(t, "https://example.com/a2", variants[0].URL)
+
+	// visit ke variant ikut dihitung di stats
+	database.DB.Create(&models.Visit{LinkID: link.ID, ClientID: user.ID, VariantID: &variant.ID})
+	stats, err := queryVariantStats(link.ID)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	assert.Equal(t, int64(1), stats[0].Visits)
+
+	req, _ = http.NewRequest("DELETE", "/links/ab001/variants/"+variant.ID.String(), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var count int64
+	database.DB.Model(&models.LinkVariant{}).Where("link_id = ?", link.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
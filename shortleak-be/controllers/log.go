@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shortleak/database"
+	"shortleak/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+/** saveLog records an action of the user in the audit log, it responds itself when the log cannot be saved */
+func saveLog(c *gin.Context, userID uuid.UUID, action string, data interface{}) bool {
+	b, _ := json.Marshal(data)
+	log := models.Log{
+		UserID: userID,
+		Action: action,
+		Data:   datatypes.JSON(b),
+	}
+	if err := database.DB.Create(&log).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"shortleak/dto"
+	"shortleak/models"
+	"shortleak/services"
+	"shortleak/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/** Mailer delivers the mail of the auth flows, set by server.SetupRouter */
+var Mailer utils.Mailer = &utils.LogMailer{}
+
+var (
+	getUserByEmail           = services.GetUserByEmail
+	createPasswordResetToken = services.CreatePasswordResetToken
+	checkPasswordResetToken  = services.CheckPasswordResetToken
+	resetPassword            = services.ResetPassword
+)
+
+/** pendingMail counts the mail still being sent in the background */
+var pendingMail sync.WaitGroup
+
+/** sendMail delivers message in the background, so a slow mail server neither holds the request nor tells by its timing that an account exists */
+func sendMail(message utils.MailMessage) {
+	mailer := Mailer
+	pendingMail.Add(1)
+	go func() {
+		defer pendingMail.Done()
+		if err := mailer.Send(message); err != nil {
+			log.Printf("❌ Failed to send mail %q: %v", message.Subject, err)
+		}
+	}()
+}
+
+/** WaitForMail returns once the mail sent in the background is delivered or failed */
+func WaitForMail() {
+	pendingMail.Wait()
+}
+
+/** passwordResetLimiter bounds the reset mails sent to one address */
+var passwordResetLimiter = utils.NewAttemptLimiter(3, time.Hour)
+
+/** passwordResetSent is returned whether or not the email is registered, so accounts cannot be discovered */
+const passwordResetSent = "If the email is registered, a password reset link has been sent"
+
+/** appURL is the configured frontend address, the local frontend when unset */
+func appURL() string {
+	if AppConfig.AppURL != "" {
+		return AppConfig.AppURL
+	}
+	return "http://localhost:5173"
+}
+
+/** passwordResetTTL is the configured reset link lifetime, one hour when unset */
+func passwordResetTTL() time.Duration {
+	if AppConfig.PasswordResetTTL > 0 {
+		return AppConfig.PasswordResetTTL
+	}
+	return time.Hour
+}
+
+func passwordResetMail(user models.User, link string, ttl time.Duration) utils.MailMessage {
+	return utils.MailMessage{
+		To:      user.Email,
+		Subject: "Reset your Shortleak password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your Shortleak account. "+
+			"Open the link below within %s to choose a new one:\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email and your password stays the same.\n",
+			user.FullName, ttl, link),
+	}
+}
+
+/** RequestPasswordReset mails a reset link to a registered email */
+func RequestPasswordReset(c *gin.Context) {
+	var req dto.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if errors := utils.ValidateStruct(req); errors != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_errors": errors})
+		return
+	}
+
+	/** Every request counts, registered or not, so the limit does not reveal accounts either */
+	limiterKey := strings.ToLower(req.Email)
+	if passwordResetLimiter.Blocked(limiterKey) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many password reset requests, try again later"})
+		return
+	}
+	passwordResetLimiter.Fail(limiterKey)
+
+	user, err := getUserByEmail(req.Email)
+	if err != nil || !user.Active {
+		c.JSON(http.StatusOK, gin.H{"message": passwordResetSent})
+		return
+	}
+
+	ttl := passwordResetTTL()
+	raw, err := createPasswordResetToken(user.ID, ttl, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+		return
+	}
+	link := appURL() + "/reset-password?token=" + url.QueryEscape(raw)
+	sendMail(passwordResetMail(*user, link, ttl))
+
+	if !saveLog(c, user.ID, "request-password-reset", map[string]interface{}{
+		"ip": c.ClientIP(),
+	}) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": passwordResetSent})
+}
+
+/** ConfirmPasswordReset sets a new password with a reset token and logs the user out everywhere */
+func ConfirmPasswordReset(c *gin.Context) {
+	var req dto.PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if errors := utils.ValidateStruct(req); errors != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_errors": errors})
+		return
+	}
+
+	/** The token is checked first so an invalid one does not cost a bcrypt hash */
+	if err := checkPasswordResetToken(req.Token, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	hashed, err := generatePasswordHash([]byte(req.Password), 10)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	user, err := resetPassword(req.Token, string(hashed), time.Now())
+	if errors.Is(err, services.ErrInvalidResetToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	passwordResetLimiter.Reset(strings.ToLower(user.Email))
+
+	if !saveLog(c, user.ID, "reset-password", map[string]interface{}{
+		"ip": c.ClientIP(),
+	}) {
+		return
+	}
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, please log in again"})
+}
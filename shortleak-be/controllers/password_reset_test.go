@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"shortleak/database"
+	"shortleak/models"
+	"shortleak/services"
+	"shortleak/utils"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+/** fakeMailer keeps sent mail for assertions */
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []utils.MailMessage
+}
+
+func (m *fakeMailer) Send(message utils.MailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+func mockMailer(t *testing.T) *fakeMailer {
+	mailer := &fakeMailer{}
+	origMailer, origLimiter := Mailer, passwordResetLimiter
+	Mailer = mailer
+	passwordResetLimiter = utils.NewAttemptLimiter(3, time.Hour)
+	t.Cleanup(func() {
+		WaitForMail()
+		Mailer, passwordResetLimiter = origMailer, origLimiter
+	})
+	return mailer
+}
+
+/** blockingMailer menahan pengiriman sampai release ditutup */
+type blockingMailer struct {
+	fakeMailer
+	release chan struct{}
+}
+
+func (m *blockingMailer) Send(message utils.MailMessage) error {
+	<-m.release
+	return m.fakeMailer.Send(message)
+}
+
+func postJSON(r *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func passwordResetRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/api/auth/login", Login)
+	r.POST("/api/auth/password-reset/request", RequestPasswordReset)
+	r.POST("/api/auth/password-reset/confirm", ConfirmPasswordReset)
+	return r
+}
+
+func TestRequestPasswordResetUnknownEmail(t *testing.T) {
+	mailer := mockMailer(t)
+	origGet := getUserByEmail
+	getUserByEmail = func(email string) (*models.User, error) {
+		return nil, errors.New("record not found")
+	}
+	defer func() { getUserByEmail = origGet }()
+
+	w := postJSON(passwordResetRouter(), "/api/auth/password-reset/request", `{"email":"nobody@example.com"}`)
+
+	// respons sama seperti email terdaftar
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), passwordResetSent)
+	WaitForMail()
+	assert.Empty(t, mailer.sent)
+}
+
+func TestRequestPasswordResetRateLimited(t *testing.T) {
+	mockMailer(t)
+	origGet := getUserByEmail
+	getUserByEmail = func(email string) (*models.User, error) {
+		return nil, errors.New("record not found")
+	}
+	defer func() { getUserByEmail = origGet }()
+	r := passwordResetRouter()
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, postJSON(r, "/api/auth/password-reset/request", `{"email":"john@example.com"}`).Code)
+	}
+	w := postJSON(r, "/api/auth/password-reset/request", `{"email":"JOHN@example.com"}`)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestConfirmPasswordResetWeakPassword(t *testing.T) {
+	w := postJSON(passwordResetRouter(), "/api/auth/password-reset/confirm", `{"token":"abc","password":"password"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "validation_errors")
+	assert.Contains(t, w.Body.String(), "1 uppercase")
+}
+
+func TestSendMailDoesNotWait(t *testing.T) {
+	mailer := &blockingMailer{release: make(chan struct{})}
+	origMailer := Mailer
+	Mailer = mailer
+	defer func() { Mailer = origMailer }()
+
+	// sendMail langsung kembali walau server mail macet
+	done := make(chan struct{})
+	go func() {
+		sendMail(utils.MailMessage{To: "john@example.com", Subject: "Hello"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sendMail waited for the mailer")
+	}
+
+	close(mailer.release)
+	WaitForMail()
+	assert.Len(t, mailer.sent, 1)
+}
+
+func TestConfirmPasswordResetInvalidToken(t *testing.T) {
+	origCheck, origReset, origHash := checkPasswordResetToken, resetPassword, generatePasswordHash
+	checkPasswordResetToken = func(raw string, now time.Time) error {
+		return services.ErrInvalidResetToken
+	}
+	resetPassword = func(raw string, hashedPassword string, now time.Time) (*models.User, error) {
+		t.Fatal("password reset with an invalid token")
+		return nil, nil
+	}
+	hashed := false
+	generatePasswordHash = func(password []byte, cost int) ([]byte, error) {
+		hashed = true
+		return bcrypt.GenerateFromPassword(password, bcrypt.MinCost)
+	}
+	defer func() {
+		checkPasswordResetToken, resetPassword, generatePasswordHash = origCheck, origReset, origHash
+	}()
+
+	w := postJSON(passwordResetRouter(), "/api/auth/password-reset/confirm", `{"token":"expired","password":"NewSecret123!"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid or expired reset token")
+	// token dicek sebelum password di-hash
+	assert.False(t, hashed)
+}
+
+func TestConfirmPasswordResetTokenUsedConcurrently(t *testing.T) {
+	origCheck, origReset := checkPasswordResetToken, resetPassword
+	checkPasswordResetToken = func(raw string, now time.Time) error {
+		return nil
+	}
+	// token dipakai request lain setelah pengecekan
+	resetPassword = func(raw string, hashedPassword string, now time.Time) (*models.User, error) {
+		return nil, services.ErrInvalidResetToken
+	}
+	defer func() { checkPasswordResetToken, resetPassword = origCheck, origReset }()
+
+	w := postJSON(passwordResetRouter(), "/api/auth/password-reset/confirm", `{"token":"used","password":"NewSecret123!"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid or expired reset token")
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	setupTestAuthDB(t)
+	t.Setenv("PLATFORM", "shortleak")
+	mailer := mockMailer(t)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("Secret123!"), bcrypt.MinCost)
+	user := models.User{ID: uuid.New(), FullName: "John Doe", Email: "john@example.com", Password: string(hashed), Active: true}
+	database.DB.Create(&user)
+	r := passwordResetRouter()
+
+	w := postJSON(r, "/api/auth/password-reset/request", `{"email":"john@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	WaitForMail()
+	assert.Len(t, mailer.sent, 1)
+	assert.Equal(t, "john@example.com", mailer.sent[0].To)
+
+	link := regexp.MustCompile(`https?://\S+`).FindString(mailer.sent[0].Body)
+	parsed, err := url.Parse(link)
+	assert.NoError(t, err)
+	token := parsed.Query().Get("token")
+	assert.NotEmpty(t, token)
+
+	// token mentah tidak disimpan
+	var stored models.PasswordResetToken
+	database.DB.First(&stored, "user_id = ?", user.ID)
+	assert.NotEqual(t, token, stored.TokenHash)
+
+	w = postJSON(r, "/api/auth/password-reset/confirm", `{"token":"`+token+`","password":"NewSecret123!"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// token tidak bisa dipakai dua kali
+	w = postJSON(r, "/api/auth/password-reset/confirm", `{"token":"`+token+`","password":"Another123!"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.Equal(t, http.StatusUnauthorized, postJSON(r, "/api/auth/login", `{"email":"john@example.com","password":"Secret123!"}`).Code)
+	assert.Equal(t, http.StatusOK, postJSON(r, "/api/auth/login", `{"email":"john@example.com","password":"NewSecret123!"}`).Code)
+
+	var logs int64
+	database.DB.Model(&models.Log{}).Where("user_id = ? AND action IN ?", user.ID, []string{"request-password-reset", "reset-password"}).Count(&logs)
+	assert.Equal(t, int64(2), logs)
+}
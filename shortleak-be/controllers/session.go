@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"net/http"
+	"shortleak/models"
+	"shortleak/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	getSession        = services.GetSession
+	getActiveSessions = services.GetActiveSessions
+	revokeAllSessions = services.RevokeAllSessions
+)
+
+/** currentSessionID is the session of the access token used for the request */
+func currentSessionID(c *gin.Context) uuid.UUID {
+	if value, exists := c.Get("session"); exists {
+		return value.(models.Session).ID
+	}
+	return uuid.Nil
+}
+
+/** GetSessions lists the active sessions of the user, marking the one making the request */
+func GetSessions(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	u := user.(models.User)
+	sessions, err := getActiveSessions(u.ID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	current := currentSessionID(c)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+/** RevokeSession logs out one device of the user */
+func RevokeSession(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	u := user.(models.User)
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	now := time.Now()
+	session, err := getSession(sessionID)
+	if err != nil || session.UserID != u.ID || !session.IsActive(now) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err := revokeSession(session.ID, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !saveLog(c, u.ID, "revoke-session", map[string]interface{}{
+		"sessionId": session.ID,
+		"userAgent": session.UserAgent,
+	}) {
+		return
+	}
+	if session.ID == currentSessionID(c) {
+		clearAuthCookies(c)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+/** LogoutAll revokes every session of the user, including the current one */
+func LogoutAll(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	u := user.(models.User)
+	revoked, err := revokeAllSessions(u.ID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !saveLog(c, u.ID, "logout-all-sessions", map[string]interface{}{
+		"revoked": revoked,
+	}) {
+		return
+	}
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all devices", "revoked": revoked})
+}
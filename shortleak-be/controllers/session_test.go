@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shortleak/database"
+	"shortleak/middlewares"
+	"shortleak/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestGetSessionsMarksCurrent(t *testing.T) {
+	user := models.User{ID: uuid.New()}
+	current := models.Session{ID: uuid.New(), UserID: user.ID}
+	other := models.Session{ID: uuid.New(), UserID: user.ID}
+	origGet := getActiveSessions
+	getActiveSessions = func(userID uuid.UUID, now time.Time) ([]models.Session, error) {
+		return []models.Session{other, current}, nil
+	}
+	defer func() { getActiveSessions = origGet }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/auth/sessions", nil)
+	c.Set("user", user)
+	c.Set("session", current)
+
+	GetSessions(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var sessions []models.Session
+	json.Unmarshal(w.Body.Bytes(), &sessions)
+	assert.Len(t, sessions, 2)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+	assert.NotContains(t, w.Body.String(), "token_hash")
+}
+
+func TestRevokeSessionOfAnotherUser(t *testing.T) {
+	session := models.Session{ID: uuid.New(), UserID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)}
+	origGet := getSession
+	getSession = func(sessionID uuid.UUID) (*models.Session, error) {
+		return &session, nil
+	}
+	defer func() { getSession = origGet }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: session.ID.String()}}
+	c.Request, _ = http.NewRequest("DELETE", "/api/auth/sessions/"+session.ID.String(), nil)
+	c.Set("user", models.User{ID: uuid.New()})
+
+	RevokeSession(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRevokeSessionInvalidID(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "not-a-uuid"}}
+	c.Request, _ = http.NewRequest("DELETE", "/api/auth/sessions/not-a-uuid", nil)
+	c.Set("user", models.User{ID: uuid.New()})
+
+	RevokeSession(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	setupTestAuthDB(t)
+	t.Setenv("PLATFORM", "shortleak")
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("Secret123!"), bcrypt.MinCost)
+	user := models.User{FullName: "John Doe", Email: "john@example.com", Password: string(hashed), Active: true}
+	database.DB.Create(&user)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/api/auth/login", Login)
+	r.POST("/api/auth/refresh", Refresh)
+	r.GET("/api/auth/sessions", middlewares.AuthRequired(), GetSessions)
+	r.DELETE("/api/auth/sessions", middlewares.AuthRequired(), LogoutAll)
+
+	type tokens struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refreshToken"`
+	}
+	login := func() tokens {
+		req, _ := http.NewRequest("POST", "/api/auth/login", bytes.NewBufferString(`{"email":"john@example.com","password":"Secret123!"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result tokens
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+	refresh := func(raw string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/auth/refresh", bytes.NewBufferString(`{"refresh_token":"`+raw+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	authorized := func(method, path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	laptop := login()
+	phone := login()
+
+	w := refresh(laptop.RefreshToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rotated tokens
+	json.Unmarshal(w.Body.Bytes(), &rotated)
+	assert.NotEqual(t, laptop.RefreshToken, rotated.RefreshToken)
+
+	w = authorized("GET", "/api/auth/sessions", rotated.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var sessions []models.Session
+	json.Unmarshal(w.Body.Bytes(), &sessions)
+	assert.Len(t, sessions, 2)
+
+	// keluar dari semua perangkat
+	w = authorized("DELETE", "/api/auth/sessions", phone.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, http.StatusUnauthorized, authorized("GET", "/api/auth/sessions", rotated.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(rotated.RefreshToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(phone.RefreshToken).Code)
+}
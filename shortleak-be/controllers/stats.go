@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"shortleak/database"
+	"shortleak/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+/** maxTimeseriesBuckets limits the size of a single timeseries response */
+const maxTimeseriesBuckets = 1000
+
+/** breakdownDimensions maps each stats breakdown to the visits column it groups by */
+var breakdownDimensions = map[string]string{
+	"referrers": "referrer_domain",
+	"browsers":  "browser",
+	"os":        "os",
+	"devices":   "device",
+	"languages": "language",
+	"countries": "country",
+	"regions":   "region",
+	"cities":    "city",
+}
+
+/** breakdownItem is the visit count of one value of a breakdown dimension */
+type breakdownItem struct {
+	Value  string `json:"value"`
+	Visits int64  `json:"visits"`
+}
+
+/** visitTailStart returns where the live tail of a link begins, the day after its latest rollup */
+var visitTailStart = func(linkID uuid.UUID) (time.Time, error) {
+	var latest sql.NullTime
+	err := database.DB.Model(&models.VisitDailyRollup{}).
+		Select("MAX(day)").
+		Where("link_id = ?", linkID).
+		Row().Scan(&latest)
+	if err != nil || !latest.Valid {
+		return time.Time{}, err
+	}
+	return latest.Time.UTC().AddDate(0, 0, 1), nil
+}
+
+/** queryVisitBreakdown returns the top values of a visits column from rollups and the live tail */
+var queryVisitBreakdown = func(linkID uuid.UUID, column string, limit int) ([]breakdownItem, error) {
+	tail, err := visitTailStart(linkID)
+	if err != nil {
+		return nil, err
+	}
+	items := []breakdownItem{}
+	err = database.DB.Raw(`
+		SELECT value, CAST(SUM(visits) AS bigint) AS visits FROM (
+			SELECT value, visits FROM visit_daily_dimensions
+			WHERE link_id = ? AND dimension = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT ? AS value, 1 AS visits FROM visits
+			WHERE link_id = ? AND created_at >= ? AND ? <> '' AND deleted_at IS NULL
+		) breakdown
+		GROUP BY value
+		ORDER BY visits DESC, value
+		LIMIT ?`,
+		linkID, column, clause.Column{Name: column}, linkID, tail, clause.Column{Name: column}, limit).
+		Scan(&items).Error
+	return items, err
+}
+
+/** breakdownLimit reads the top query parameter, defaults to 5 and is capped at 50 */
+func breakdownLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.DefaultQuery("top", "5"))
+	if err != nil || limit < 1 {
+		return 5
+	}
+	if limit > 50 {
+		return 50
+	}
+	return limit
+}
+
+/** linkBreakdowns returns the top-N values of every breakdown dimension of a link */
+func linkBreakdowns(linkID uuid.UUID, limit int) (gin.H, error) {
+	breakdowns := gin.H{}
+	for name, column := range breakdownDimensions {
+		items, err := queryVisitBreakdown(linkID, column, limit)
+		if err != nil {
+			return nil, err
+		}
+		breakdowns[name] = items
+	}
+	return breakdowns, nil
+}
+
+/** variantStats is the traffic sent to one weighted destination of a link */
+type variantStats struct {
+	ID             uuid.UUID `json:"id"`
+	Name           string    `json:"name"`
+	URL            string    `json:"url"`
+	Weight         int       `json:"weight"`
+	Visits         int64     `json:"visits"`
+	UniqueVisitors int64     `json:"uniqueVisitors"`
+}
+
+/** queryVariantStats counts the visits of every current variant of a link, variants are not rolled up */
+var queryVariantStats = func(linkID uuid.UUID) ([]variantStats, error) {
+	stats := []variantStats{}
+	err := database.DB.Raw(`
+		SELECT link_variants.id, link_variants.name, link_variants.url, link_variants.weight,
+			COUNT(visits.id) AS visits, COUNT(DISTINCT visits.client_id) AS unique_visitors
+		FROM link_variants
+		LEFT JOIN visits ON visits.link_id = link_variants.link_id AND visits.variant_id = link_variants.id AND visits.deleted_at IS NULL
+		WHERE link_variants.link_id = ? AND link_variants.deleted_at IS NULL
+		GROUP BY link_variants.id
+		ORDER BY link_variants.created_at, link_variants.id`, linkID).
+		Scan(&stats).Error
+	return stats, err
+}
+
+/** visitBucket is a visit count aggregated over one time bucket */
+type visitBucket struct {
+	Bucket         time.Time `json:"time"`
+	Visits         int64     `json:"visits"`
+	UniqueVisitors int64     `json:"uniqueVisitors"`
+}
+
+/** queryVisitTimeseries aggregates visits of a link per interval in the given timezone */
+var queryVisitTimeseries = func(linkID uuid.UUID, interval string, loc *time.Location, from, to time.Time) ([]visitBucket, error) {
+	var rows []visitBucket
+	err := database.DB.Model(&models.Visit{}).
+		Select("date_trunc(?, created_at AT TIME ZONE ?) AS bucket, COUNT(*) AS visits, COUNT(DISTINCT client_id) AS unique_visitors", interval, loc.String()).
+		Where("link_id = ?", linkID).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("bucket").
+		Order("bucket").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	/** Buckets are local wall-clock times without zone, attach the requested location */
+	for i, row := range rows {
+		b := row.Bucket
+		rows[i].Bucket = time.Date(b.Year(), b.Month(), b.Day(), b.Hour(), 0, 0, 0, loc)
+	}
+	return rows, nil
+}
+
+/** queryVisitRollups returns the daily rollups of a link for UTC days in [from, to) */
+var queryVisitRollups = func(linkID uuid.UUID, from, to time.Time) ([]visitBucket, error) {
+	var rows []visitBucket
+	err := database.DB.Model(&models.VisitDailyRollup{}).
+		Select("day AS bucket, visits, unique_visitors").
+		Where("link_id = ? AND day >= ? AND day < ?", linkID, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("day").
+		Scan(&rows).Error
+	for i, row := range rows {
+		rows[i].Bucket = row.Bucket.UTC()
+	}
+	return rows, err
+}
+
+/** queryDailyTimeseries serves UTC daily buckets from rollups and the days after them from raw visits */
+func queryDailyTimeseries(linkID uuid.UUID, from, to time.Time) ([]visitBucket, error) {
+	tail, err := visitTailStart(linkID)
+	if err != nil {
+		return nil, err
+	}
+	/** Rolled up days are whole days, a partially covered day at either end is included entirely */
+	rows := []visitBucket{}
+	rollupStart := truncateToInterval(from, "day")
+	rollupEnd := truncateToInterval(minTime(tail, to), "day")
+	if rollupEnd.Before(minTime(tail, to)) {
+		rollupEnd = rollupEnd.AddDate(0, 0, 1)
+	}
+	if rollupStart.Before(rollupEnd) {
+		if rows, err = queryVisitRollups(linkID, rollupStart, rollupEnd); err != nil {
+			return nil, err
+		}
+	}
+	if liveStart := maxTime(tail, from); liveStart.Before(to) {
+		live, err := queryVisitTimeseries(linkID, "day", time.UTC, liveStart, to)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, live...)
+	}
+	return rows, nil
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+/** truncateToInterval returns the start of the bucket containing t, weeks start on Monday */
+func truncateToInterval(t time.Time, interval string) time.Time {
+	switch interval {
+	case "hour":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+/** nextInterval returns the start of the bucket following start */
+func nextInterval(start time.Time, interval string) time.Time {
+	switch interval {
+	case "hour":
+		return truncateToInterval(start.Add(time.Hour), interval)
+	case "week":
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+/** fillVisitBuckets returns every bucket between from and to, including empty ones */
+func fillVisitBuckets(rows []visitBucket, interval string, from, to time.Time) []visitBucket {
+	counts := make(map[int64]visitBucket, len(rows))
+	for _, row := range rows {
+		counts[row.Bucket.Unix()] = row
+	}
+	buckets := []visitBucket{}
+	for t := truncateToInterval(from, interval); t.Before(to); t = nextInterval(t, interval) {
+		bucket := visitBucket{Bucket: t}
+		if row, ok := counts[t.Unix()]; ok {
+			bucket.Visits = row.Visits
+			bucket.UniqueVisitors = row.UniqueVisitors
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+/** parseTimeParam parses RFC3339 timestamps or plain dates in the given location */
+func parseTimeParam(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
+
+/** defaultTimeseriesRange returns how far back a timeseries goes when from is omitted */
+func defaultTimeseriesRange(to time.Time, interval string) time.Time {
+	switch interval {
+	case "hour":
+		return to.Add(-24 * time.Hour)
+	case "week":
+		return to.AddDate(0, 0, -7*12)
+	default:
+		return to.AddDate(0, 0, -30)
+	}
+}
+
+func GetLinkTimeseries(c *gin.Context) {
+	link, _, ok := authorizeLink(c)
+	if !ok {
+		return
+	}
+
+	/** Validate interval */
+	interval := c.DefaultQuery("interval", "day")
+	if interval != "hour" && interval != "day" && interval != "week" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interval, use hour, day or week"})
+		return
+	}
+
+	/** Validate timezone */
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	/** Validate time range */
+	to := time.Now().In(loc)
+	if value := c.Query("to"); value != "" {
+		if to, err = parseTimeParam(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, use RFC3339 or YYYY-MM-DD"})
+			return
+		}
+	}
+	from := defaultTimeseriesRange(to, interval)
+	if value := c.Query("from"); value != "" {
+		if from, err = parseTimeParam(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, use RFC3339 or YYYY-MM-DD"})
+			return
+		}
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+	bucketSize := map[string]time.Duration{"hour": time.Hour, "day": 24 * time.Hour, "week": 7 * 24 * time.Hour}[interval]
+	if to.Sub(from)/bucketSize > maxTimeseriesBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Time range too large for the interval"})
+		return
+	}
+
+	/** Rollups are per UTC day, other intervals and timezones are aggregated from raw visits */
+	var rows []visitBucket
+	if interval == "day" && loc.String() == "UTC" {
+		rows, err = queryDailyTimeseries(link.ID, from, to)
+	} else {
+		rows, err = queryVisitTimeseries(link.ID, interval, loc, from, to)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shortToken": link.ShortToken,
+		"interval":   interval,
+		"timezone":   loc.String(),
+		"from":       from,
+		"to":         to,
+		"buckets":    fillVisitBuckets(rows, interval, from, to),
+	})
+}
+
+/** GetVisitQueueStats reports the visit recorder queue depth and drop counters to admins */
+func GetVisitQueueStats(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	if user.(models.User).Role != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+	c.JSON(http.StatusOK, VisitRecorder.Stats())
+}
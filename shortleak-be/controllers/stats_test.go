@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"shortleak/models"
+	"shortleak/services"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockOwnedLink(t *testing.T, owner models.User) {
+	orig := getLinkByShortToken
+	getLinkByShortToken = func(token string) (*models.Link, error) {
+		return &models.Link{URL: "http://example.com", ShortToken: token, UserID: owner.ID, Active: true}, nil
+	}
+	t.Cleanup(func() { getLinkByShortToken = orig })
+}
+
+func TestFillVisitBucketsDay(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	from := time.Date(2025, 10, 1, 10, 0, 0, 0, loc)
+	to := time.Date(2025, 10, 4, 0, 0, 0, 0, loc)
+	rows := []visitBucket{
+		{Bucket: time.Date(2025, 10, 2, 0, 0, 0, 0, loc), Visits: 5, UniqueVisitors: 3},
+	}
+
+	buckets := fillVisitBuckets(rows, "day", from, to)
+
+	// 1, 2, 3 Oktober, bucket kosong tetap muncul
+	assert.Len(t, buckets, 3)
+	assert.Equal(t, int64(0), buckets[0].Visits)
+	assert.Equal(t, int64(5), buckets[1].Visits)
+	assert.Equal(t, int64(3), buckets[1].UniqueVisitors)
+	assert.Equal(t, time.Date(2025, 10, 3, 0, 0, 0, 0, loc), buckets[2].Bucket)
+}
+
+func TestTruncateToIntervalWeekStartsMonday(t *testing.T) {
+	sunday := time.Date(2025, 10, 19, 15, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 10, 13, 0, 0, 0, 0, time.UTC), truncateToInterval(sunday, "week"))
+	assert.Equal(t, time.Date(2025, 10, 19, 15, 0, 0, 0, time.UTC), truncateToInterval(sunday, "hour"))
+}
+
+func TestGetLinkTimeseriesInvalidParams(t *testing.T) {
+	owner := models.User{ID: uuid.New()}
+	mockOwnedLink(t, owner)
+
+	cases := map[string]string{
+		"interval":  "/stats/abcde/timeseries?interval=month",
+		"timezone":  "/stats/abcde/timeseries?tz=Mars/Olympus",
+		"from":      "/stats/abcde/timeseries?from=yesterday",
+		"range":     "/stats/abcde/timeseries?from=2025-10-05&to=2025-10-01",
+		"too large": "/stats/abcde/timeseries?interval=hour&from=2020-01-01&to=2025-01-01",
+	}
+	for name, url := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+			c.Request, _ = http.NewRequest("GET", url, nil)
+			c.Set("user", owner)
+
+			GetLinkTimeseries(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestGetLinkTimeseriesSuccess(t *testing.T) {
+	owner := models.User{ID: uuid.New()}
+	mockOwnedLink(t, owner)
+
+	orig := queryVisitTimeseries
+	queryVisitTimeseries = func(linkID uuid.UUID, interval string, loc *time.Location, from, to time.Time) ([]visitBucket, error) {
+		assert.Equal(t, "hour", interval)
+		assert.Equal(t, "Asia/Jakarta", loc.String())
+		return []visitBucket{{Bucket: time.Date(2025, 10, 1, 1, 0, 0, 0, loc), Visits: 2, UniqueVisitors: 1}}, nil
+	}
+	defer func() { queryVisitTimeseries = orig }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("GET", "/stats/abcde/timeseries?interval=hour&tz=Asia/Jakarta&from=2025-10-01&to=2025-10-02", nil)
+	c.Set("user", owner)
+
+	GetLinkTimeseries(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `"time":"2025-10-01T01:00:00+07:00","visits":2,"uniqueVisitors":1`)
+	assert.Contains(t, body, `"timezone":"Asia/Jakarta"`)
+}
+
+func TestGetLinkTimeseriesDailyFromRollups(t *testing.T) {
+	owner := models.User{ID: uuid.New()}
+	mockOwnedLink(t, owner)
+
+	// rollup sudah sampai 2 Oktober, sisanya dari live tail
+	tail := time.Date(2025, 10, 3, 0, 0, 0, 0, time.UTC)
+	origTail := visitTailStart
+	visitTailStart = func(linkID uuid.UUID) (time.Time, error) { return tail, nil }
+	defer func() { visitTailStart = origTail }()
+
+	origRollups := queryVisitRollups
+	queryVisitRollups = func(linkID uuid.UUID, from, to time.Time) ([]visitBucket, error) {
+		assert.Equal(t, time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC), from)
+		assert.Equal(t, tail, to)
+		return []visitBucket{{Bucket: time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC), Visits: 7, UniqueVisitors: 4}}, nil
+	}
+	defer func() { queryVisitRollups = origRollups }()
+
+	origRaw := queryVisitTimeseries
+	queryVisitTimeseries = func(linkID uuid.UUID, interval string, loc *time.Location, from, to time.Time) ([]visitBucket, error) {
+		assert.Equal(t, tail, from)
+		return []visitBucket{{Bucket: tail, Visits: 2, UniqueVisitors: 2}}, nil
+	}
+	defer func() { queryVisitTimeseries = origRaw }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("GET", "/stats/abcde/timeseries?from=2025-10-01&to=2025-10-04", nil)
+	c.Set("user", owner)
+
+	GetLinkTimeseries(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `{"time":"2025-10-01T00:00:00Z","visits":0,"uniqueVisitors":0}`)
+	assert.Contains(t, body, `{"time":"2025-10-02T00:00:00Z","visits":7,"uniqueVisitors":4}`)
+	assert.Contains(t, body, `{"time":"2025-10-03T00:00:00Z","visits":2,"uniqueVisitors":2}`)
+}
+
+func TestGetLinkStatsBreakdowns(t *testing.T) {
+	owner := models.User{ID: uuid.New()}
+	mockOwnedLink(t, owner)
+
+	origCount := countVisits
+	countVisits = func(linkID uuid.UUID) (int64, error) { return 3, nil }
+	defer func() { countVisits = origCount }()
+	origUnique := countUniqueVisitors
+	countUniqueVisitors = func(linkID uuid.UUID) (int64, error) { return 2, nil }
+	defer func() { countUniqueVisitors = origUnique }()
+
+	limits := []int{}
+	origBreakdown := queryVisitBreakdown
+	queryVisitBreakdown = func(linkID uuid.UUID, column string, limit int) ([]breakdownItem, error) {
+		limits = append(limits, limit)
+		if column == "browser" {
+			return []breakdownItem{{Value: "Firefox", Visits: 2}, {Value: "Chrome", Visits: 1}}, nil
+		}
+		if column == "country" {
+			return []breakdownItem{{Value: "ID", Visits: 3}}, nil
+		}
+		return []breakdownItem{}, nil
+	}
+	defer func() { queryVisitBreakdown = origBreakdown }()
+	origVariants := queryVariantStats
+	queryVariantStats = func(linkID uuid.UUID) ([]variantStats, error) {
+		return []variantStats{{Name: "A", URL: "https://example.com/a", Weight: 1, Visits: 3, UniqueVisitors: 2}}, nil
+	}
+	defer func() { queryVariantStats = origVariants }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "shortToken", Value: "abcde"}}
+	c.Request, _ = http.NewRequest("GET", "/stats/abcde?top=3", nil)
+	c.Set("user", owner)
+
+	GetLinkStats(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `"browsers":[{"value":"Firefox","visits":2},{"value":"Chrome","visits":1}]`)
+	assert.Contains(t, body, `"referrers":[]`)
+	assert.Contains(t, body, `"countries":[{"value":"ID","visits":3}]`)
+	assert.Contains(t, body, `"name":"A","url":"https://example.com/a","weight":1,"visits":3,"uniqueVisitors":2`)
+	assert.Len(t, limits, len(breakdownDimensions))
+	assert.Equal(t, 3, limits[0])
+}
+
+func TestGetVisitQueueStats(t *testing.T) {
+	orig := VisitRecorder
+	VisitRecorder = services.NewVisitRecorder(1, 1, time.Hour, func([]services.VisitEvent) error { return nil })
+	defer func() { VisitRecorder = orig }()
+	VisitRecorder.Record(services.VisitEvent{})
+	VisitRecorder.Record(services.VisitEvent{})
+
+	// user biasa tidak boleh lihat
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user", models.User{Role: models.RoleUser})
+	GetVisitQueueStats(c)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Set("user", models.User{Role: models.RoleAdmin})
+	GetVisitQueueStats(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"queued":1,"capacity":1,"enqueued":1,"dropped":1`)
+}
@@ -44,6 +44,248 @@ var migrations = []*gormigrate.Migration{
 			return tx.Migrator().DropTable("logs")
 		},
 	},
+	{
+		ID: "20251017_link_expiration_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Link{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&models.Link{}, "expires_at"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&models.Link{}, "max_clicks")
+		},
+	},
+	{
+		ID: "20251017_link_history_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.LinkHistory{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("link_histories")
+		},
+	},
+	{
+		ID: "20251017_link_url_per_user_migration",
+		Migrate: func(tx *gorm.DB) error {
+			/** Drop the global unique URL constraint, uniqueness is now checked per user */
+			if tx.Migrator().HasConstraint(&models.Link{}, "uni_links_url") {
+				if err := tx.Migrator().DropConstraint(&models.Link{}, "uni_links_url"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasIndex(&models.Link{}, "idx_links_url") {
+				if err := tx.Migrator().DropIndex(&models.Link{}, "idx_links_url"); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasIndex(&models.Link{}, "idx_links_user_url") {
+				return tx.Migrator().CreateIndex(&models.Link{}, "idx_links_user_url")
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&models.Link{}, "idx_links_user_url"); err != nil {
+				return err
+			}
+			return tx.Exec("ALTER TABLE links ADD CONSTRAINT uni_links_url UNIQUE (url)").Error
+		},
+	},
+	{
+		ID: "20251017_user_role_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.User{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.User{}, "role")
+		},
+	},
+	{
+		ID: "20251017_link_password_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Link{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.Link{}, "password")
+		},
+	},
+	{
+		ID: "20251017_visit_migration",
+		Migrate: func(tx *gorm.DB) error {
+			/** JSON operators on logs.data need jsonb to be indexable */
+			if err := tx.Exec("ALTER TABLE logs ALTER COLUMN data TYPE jsonb USING data::jsonb").Error; err != nil {
+				return err
+			}
+			if err := tx.AutoMigrate(&models.Visit{}); err != nil {
+				return err
+			}
+			/** Copy existing visit logs, the log ID is reused so the backfill can run again safely */
+			return tx.Exec(`
+				INSERT INTO visits (id, created_at, updated_at, link_id, client_id,
+					referrer, referrer_domain, user_agent, browser, os, device,
+					accept_language, language, country, region, city)
+				SELECT logs.id, logs.created_at, logs.updated_at, links.id, logs.user_id,
+					COALESCE(logs.data->>'referrer', ''), COALESCE(logs.data->>'referrerDomain', ''),
+					COALESCE(logs.data->>'userAgent', ''), COALESCE(logs.data->>'browser', ''),
+					COALESCE(logs.data->>'os', ''), COALESCE(logs.data->>'device', ''),
+					COALESCE(logs.data->>'acceptLanguage', ''), COALESCE(logs.data->>'language', ''),
+					COALESCE(logs.data->>'country', ''), COALESCE(logs.data->>'region', ''),
+					COALESCE(logs.data->>'city', '')
+				FROM logs
+				JOIN links ON links.short_token = logs.data->>'shortToken'
+				WHERE logs.action = 'visit-link' AND logs.deleted_at IS NULL
+				ON CONFLICT (id) DO NOTHING`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable("visits"); err != nil {
+				return err
+			}
+			return tx.Exec("ALTER TABLE logs ALTER COLUMN data TYPE json USING data::json").Error
+		},
+	},
+	{
+		ID: "20251017_visit_rollup_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Visit{}, &models.VisitDailyRollup{}, &models.VisitDailyDimension{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable("visit_daily_dimensions", "visit_daily_rollups"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropIndex(&models.Visit{}, "idx_visits_link_client")
+		},
+	},
+	{
+		ID: "20251017_link_open_graph_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Link{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, column := range []string{"og_title", "og_description", "og_image", "og_site_name", "og_fetched_at"} {
+				if err := tx.Migrator().DropColumn(&models.Link{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "20251017_link_redirect_type_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Link{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.Link{}, "redirect_type")
+		},
+	},
+	{
+		ID: "20251017_link_forwarding_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Link{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, column := range []string{"forward_query", "forward_path"} {
+				if err := tx.Migrator().DropColumn(&models.Link{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "20251017_link_rule_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.LinkRule{}, &models.Visit{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&models.Visit{}, "rule_id"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable("link_rules")
+		},
+	},
+	{
+		ID: "20251017_link_variant_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.LinkVariant{}, &models.Visit{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&models.Visit{}, "idx_visits_link_variant"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&models.Visit{}, "variant_id"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable("link_variants")
+		},
+	},
+	{
+		ID: "20251017_link_rule_country_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.LinkRule{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.LinkRule{}, "country")
+		},
+	},
+	{
+		ID: "20251017_api_key_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.APIKey{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("api_keys")
+		},
+	},
+	{
+		ID: "20251017_session_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Session{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("sessions")
+		},
+	},
+	{
+		ID: "20251017_password_reset_token_migration",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.PasswordResetToken{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("password_reset_tokens")
+		},
+	},
+	{
+		ID: "20251017_user_email_verification_migration",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.User{}); err != nil {
+				return err
+			}
+			/** Accounts created before verification existed keep working */
+			return tx.Model(&models.User{}).
+				Where("email_verified_at IS NULL").
+				Update("email_verified_at", gorm.Expr("created_at")).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.User{}, "email_verified_at")
+		},
+	},
+	{
+		ID: "20251017_link_clicks_migration",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.Link{}); err != nil {
+				return err
+			}
+			/** Start the counter of limited links from the visits recorded so far */
+			return tx.Exec(`
+				UPDATE links SET clicks = (
+					SELECT COUNT(*) FROM visits WHERE visits.link_id = links.id AND visits.deleted_at IS NULL
+				) WHERE max_clicks IS NOT NULL`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.Link{}, "clicks")
+		},
+	},
 }
 
 func Migrate(db *gorm.DB) error {
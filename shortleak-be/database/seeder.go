@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"shortleak/models"
+	"time"
 
 	"github.com/xuri/excelize/v2"
 	"golang.org/x/crypto/bcrypt"
@@ -39,10 +40,13 @@ func SeedUsersFromExcel(filePath string) error {
 			continue
 		}
 
+		/** Seeded accounts are trusted, they skip email verification */
+		verifiedAt := time.Now()
 		user := models.User{
-			FullName: row[0],
-			Email:    row[1],
-			Password: string(hashed),
+			FullName:        row[0],
+			Email:           row[1],
+			Password:        string(hashed),
+			EmailVerifiedAt: &verifiedAt,
 		}
 
 		if err := DB.Create(&user).Error; err != nil {
@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type RegisterRequest struct {
 	FullName string `json:"fullname" validate:"required,min=3"`
 	Email    string `json:"email" validate:"required,email"`
@@ -10,3 +12,27 @@ type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
 }
+
+/** RefreshRequest carries the refresh token of clients without cookies, browsers send it as a cookie */
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type APIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=64"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
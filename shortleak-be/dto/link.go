@@ -1,5 +1,56 @@
 package dto
 
+import "time"
+
 type LinkRequest struct {
-	URL string `json:"url" validate:"required,url"`
+	URL       string     `json:"url" validate:"required,url"`
+	Alias     string     `json:"alias" validate:"omitempty,min=3,max=32,alias"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxClicks *int64     `json:"max_clicks" validate:"omitempty,min=1"`
+	Password  string     `json:"password" validate:"omitempty,min=4"`
+	/** AllowDuplicate creates a new link even if the user already shortened the URL */
+	AllowDuplicate bool `json:"allow_duplicate"`
+	/** RedirectType is 301, 302, 307 or 308, empty uses the server default */
+	RedirectType int `json:"redirect_type" validate:"omitempty,oneof=301 302 307 308"`
+	/** ForwardQuery merges the visitor query string into the destination, destination parameters win */
+	ForwardQuery bool `json:"forward_query"`
+	/** ForwardPath appends the path after the short token to the destination path */
+	ForwardPath bool `json:"forward_path"`
+}
+
+/** LinkRuleRequest targets visitors by User-Agent and Accept-Language, empty conditions match anything */
+type LinkRuleRequest struct {
+	Priority int    `json:"priority"`
+	OS       string `json:"os" validate:"omitempty,max=32"`
+	Device   string `json:"device" validate:"omitempty,oneof=desktop mobile tablet bot"`
+	Language string `json:"language" validate:"omitempty,max=8"`
+	/** Country is one or more ISO country codes separated by commas, e.g. "DE,AT,CH" */
+	Country string `json:"country" validate:"omitempty,max=255"`
+	URL     string `json:"url" validate:"required,url"`
+}
+
+/** LinkVariantRequest is one weighted destination of an A/B split */
+type LinkVariantRequest struct {
+	Name   string `json:"name" validate:"omitempty,max=64"`
+	URL    string `json:"url" validate:"required,url"`
+	Weight int    `json:"weight" validate:"required,min=1,max=1000"`
+}
+
+/** PublicLinkResponse is what anyone with the short token may see of a link, without its owner or settings */
+type PublicLinkResponse struct {
+	ShortToken        string `json:"short_token"`
+	URL               string `json:"url"`
+	PasswordProtected bool   `json:"password_protected"`
+	OGTitle           string `json:"og_title"`
+	OGDescription     string `json:"og_description"`
+	OGImage           string `json:"og_image"`
+	OGSiteName        string `json:"og_site_name"`
+}
+
+type UpdateLinkRequest struct {
+	/** URL may be left empty when only the redirect options change */
+	URL          string `json:"url" validate:"omitempty,url"`
+	RedirectType *int   `json:"redirect_type" validate:"omitempty,oneof=0 301 302 307 308"`
+	ForwardQuery *bool  `json:"forward_query"`
+	ForwardPath  *bool  `json:"forward_path"`
 }
@@ -2,7 +2,10 @@ module shortleak
 
 go 1.24.0
 
-require gorm.io/datatypes v1.2.6
+require (
+	github.com/oschwald/maxminddb-golang v1.13.1
+	gorm.io/datatypes v1.2.6
+)
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
@@ -1,10 +1,42 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"shortleak/server"
+	"syscall"
+	"time"
 )
 
 func main() {
 	r := server.SetupRouter()
-	r.Run(":8090")
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	workers := server.StartWorkers(workersCtx)
+
+	srv := &http.Server{Addr: ":8090", Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	/** Wait for a shutdown signal */
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	/** Stop accepting requests first so no visit is queued after the final flush */
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("❌ Server shutdown failed:", err)
+	}
+	stopWorkers()
+	workers.Wait()
+	log.Println("✅ Server stopped")
 }
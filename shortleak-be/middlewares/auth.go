@@ -3,8 +3,10 @@ package middlewares
 import (
 	"net/http"
 	"os"
-	"shortleak/database"
 	"shortleak/models"
+	"shortleak/services"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -12,41 +14,122 @@ import (
 )
 
 var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+var authenticateAPIKey = services.AuthenticateAPIKey
+
+var validateSession = services.ValidateSession
+
+/** bearerToken returns the token of an "Authorization: Bearer" header, empty when there is none */
+func bearerToken(c *gin.Context) string {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+/** authenticate sets the user of a request from its credentials, it returns the error to respond with when they are missing or invalid */
+func authenticate(c *gin.Context) string {
+	/** The Authorization header takes precedence over the cookie, an invalid header is not retried with the cookie */
+	tokenString := bearerToken(c)
+
+	/** API keys are sent as bearer tokens and recognised by their prefix */
+	if strings.HasPrefix(tokenString, models.APIKeyPrefix) {
+		key, err := authenticateAPIKey(tokenString, time.Now())
+		if err != nil {
+			return "Invalid API key"
+		}
+		/** The owner may have been deleted after the key was created */
+		if key.User.ID == uuid.Nil {
+			return "User not found"
+		}
+		c.Set("user", key.User)
+		c.Set("api_key", *key)
+		return ""
+	}
+
+	/** Fall back to the token cookie set by Login */
+	if tokenString == "" {
+		cookie, err := c.Cookie(os.Getenv("PLATFORM"))
+		if err != nil || cookie == "" {
+			return "Unauthorized"
+		}
+		tokenString = cookie
+	}
+
+	/** Parse the token */
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	/** Check if token is valid */
+	if err != nil || !token.Valid {
+		return "Invalid token"
+	}
+	sessionID, ok := claims["sessionId"].(string)
+	if !ok {
+		return "Invalid token"
+	}
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return "Invalid token"
+	}
+
+	/** Check the session was not logged out, it carries the user */
+	session, err := validateSession(id, time.Now())
+	if err != nil {
+		return "Session expired or revoked"
+	}
+
+	/** Set user in context */
+	c.Set("user", session.User)
+	c.Set("session", *session)
+	return ""
+}
 
 /** AuthRequired is a middleware to protect routes that require authentication */
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		/** Get token from cookie */
-		tokenString, err := c.Cookie(os.Getenv("PLATFORM"))
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		if message := authenticate(c); message != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": message})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		/** Parse the token */
-		claims := jwt.MapClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
+/** OptionalAuth sets the user like AuthRequired when the credentials are valid and lets every request through */
+func OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticate(c)
+		c.Next()
+	}
+}
 
-		/** Check if token is valid */
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
+/** RequireScope limits requests made with an API key to the routes of its scopes, sessions may call every route */
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if value, ok := c.Get("api_key"); ok {
+			key := value.(models.APIKey)
+			if !key.HasScope(scope) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing the " + scope + " scope"})
+				c.Abort()
+				return
+			}
 		}
+		c.Next()
+	}
+}
 
-		/** Check if user exists */
-		var user models.User
-		if err := database.DB.First(&user, "id = ?", claims["userId"]).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+/** SessionOnly rejects requests made with an API key, for routes that manage credentials */
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_key"); ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot be used for this route"})
 			c.Abort()
 			return
 		}
-
-		/** Set user in context */
-		c.Set("user", user)
 		c.Next()
 	}
 }
@@ -54,7 +137,7 @@ func AuthRequired() gin.HandlerFunc {
 func ClientIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		clientID, err := c.Cookie("client_id")
-		if err != nil {
+		if _, parseErr := uuid.Parse(clientID); err != nil || parseErr != nil {
 			/** Generate new client ID */
 			clientID = uuid.New().String()
 			c.SetCookie("client_id", clientID, 3600*24*365, "/", "", false, true)
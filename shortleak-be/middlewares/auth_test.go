@@ -0,0 +1,281 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"shortleak/models"
+	"shortleak/services"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockAuthenticateAPIKey(t *testing.T, key *models.APIKey) {
+	orig := authenticateAPIKey
+	authenticateAPIKey = func(raw string, now time.Time) (*models.APIKey, error) {
+		if key == nil || raw != models.APIKeyPrefix+"valid" {
+			return nil, services.ErrInvalidAPIKey
+		}
+		return key, nil
+	}
+	t.Cleanup(func() { authenticateAPIKey = orig })
+}
+
+func newAuthRouter(handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	handlers = append([]gin.HandlerFunc{AuthRequired()}, handlers...)
+	handlers = append(handlers, func(c *gin.Context) {
+		user := c.MustGet("user").(models.User)
+		c.JSON(http.StatusOK, gin.H{"userId": user.ID})
+	})
+	r.GET("/protected", handlers...)
+	return r
+}
+
+func serveWithAuthorization(r *gin.Engine, authorization string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/protected", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthRequiredAPIKey(t *testing.T) {
+	user := models.User{ID: uuid.New()}
+	mockAuthenticateAPIKey(t, &models.APIKey{ID: uuid.New(), User: user, Scopes: models.ScopeLinksWrite})
+
+	w := serveWithAuthorization(newAuthRouter(), "Bearer "+models.APIKeyPrefix+"valid")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), user.ID.String())
+}
+
+func TestAuthRequiredInvalidAPIKey(t *testing.T) {
+	mockAuthenticateAPIKey(t, nil)
+
+	w := serveWithAuthorization(newAuthRouter(), "Bearer "+models.APIKeyPrefix+"revoked")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid API key")
+}
+
+func TestAuthRequiredAPIKeyWithoutUser(t *testing.T) {
+	// pemilik key sudah dihapus, preload User kosong
+	mockAuthenticateAPIKey(t, &models.APIKey{ID: uuid.New(), Scopes: models.ScopeLinksWrite})
+
+	w := serveWithAuthorization(newAuthRouter(), "Bearer "+models.APIKeyPrefix+"valid")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "User not found")
+}
+
+func TestAuthRequiredWithoutCredentials(t *testing.T) {
+	w := serveWithAuthorization(newAuthRouter(), "")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRequireScope(t *testing.T) {
+	mockAuthenticateAPIKey(t, &models.APIKey{ID: uuid.New(), User: models.User{ID: uuid.New()}, Scopes: models.ScopeLinksRead + " " + models.ScopeStatsRead})
+
+	w := serveWithAuthorization(newAuthRouter(RequireScope(models.ScopeStatsRead)), "Bearer "+models.APIKeyPrefix+"valid")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// key tanpa scope links:write ditolak
+	w = serveWithAuthorization(newAuthRouter(RequireScope(models.ScopeLinksWrite)), "Bearer "+models.APIKeyPrefix+"valid")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "links:write")
+}
+
+func TestSessionOnlyRejectsAPIKey(t *testing.T) {
+	mockAuthenticateAPIKey(t, &models.APIKey{ID: uuid.New(), User: models.User{ID: uuid.New()}, Scopes: models.ScopeLinksWrite})
+
+	w := serveWithAuthorization(newAuthRouter(SessionOnly()), "Bearer "+models.APIKeyPrefix+"valid")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRequireScopeAllowsSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/protected", func(c *gin.Context) {
+		c.Set("user", models.User{ID: uuid.New()})
+	}, RequireScope(models.ScopeLinksWrite), SessionOnly(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	req, _ := http.NewRequest("GET", "/protected", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+/** mockValidateSession makes one active session per user, keyed by the user ID */
+func mockValidateSession(t *testing.T, users ...models.User) {
+	orig := validateSession
+	validateSession = func(id uuid.UUID, now time.Time) (*models.Session, error) {
+		for _, user := range users {
+			if user.ID == id {
+				return &models.Session{ID: id, UserID: user.ID, User: user}, nil
+			}
+		}
+		return nil, services.ErrInvalidSession
+	}
+	t.Cleanup(func() { validateSession = orig })
+}
+
+func signTestToken(t *testing.T, userID uuid.UUID, exp time.Time) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userId":    userID,
+		"sessionId": userID,
+		"exp":       exp.Unix(),
+	})
+	tokenString, err := token.SignedString(jwtSecret)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return tokenString
+}
+
+func TestAuthRequiredBearerJWT(t *testing.T) {
+	user := models.User{ID: uuid.New()}
+	mockValidateSession(t, user)
+
+	w := serveWithAuthorization(newAuthRouter(), "Bearer "+signTestToken(t, user.ID, time.Now().Add(time.Hour)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), user.ID.String())
+}
+
+func TestAuthRequiredExpiredBearerJWT(t *testing.T) {
+	user := models.User{ID: uuid.New()}
+	mockValidateSession(t, user)
+
+	w := serveWithAuthorization(newAuthRouter(), "Bearer "+signTestToken(t, user.ID, time.Now().Add(-time.Hour)))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid token")
+}
+
+func TestAuthRequiredRejectsUnsignedJWT(t *testing.T) {
+	user := models.User{ID: uuid.New()}
+	mockValidateSession(t, user)
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"userId": user.ID, "sessionId": user.ID, "exp": time.Now().Add(time.Hour).Unix()})
+	tokenString, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	w := serveWithAuthorization(newAuthRouter(), "Bearer "+tokenString)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthRequiredHeaderTakesPrecedenceOverCookie(t *testing.T) {
+	headerUser := models.User{ID: uuid.New()}
+	cookieUser := models.User{ID: uuid.New()}
+	mockValidateSession(t, headerUser, cookieUser)
+	t.Setenv("PLATFORM", "shortleak")
+	r := newAuthRouter()
+	cookie := &http.Cookie{Name: os.Getenv("PLATFORM"), Value: signTestToken(t, cookieUser.ID, time.Now().Add(time.Hour))}
+
+	// header yang valid dipakai walaupun cookie juga ada
+	req, _ := http.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestToken(t, headerUser.ID, time.Now().Add(time.Hour)))
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), headerUser.ID.String())
+
+	// header yang tidak valid tidak jatuh kembali ke cookie
+	req, _ = http.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// tanpa header, cookie tetap dipakai
+	req, _ = http.NewRequest("GET", "/protected", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), cookieUser.ID.String())
+}
+
+func TestAuthRequiredRevokedSession(t *testing.T) {
+	mockValidateSession(t)
+
+	w := serveWithAuthorization(newAuthRouter(), "Bearer "+signTestToken(t, uuid.New(), time.Now().Add(time.Hour)))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Session expired or revoked")
+}
+
+func TestAuthRequiredTokenWithoutSession(t *testing.T) {
+	user := models.User{ID: uuid.New()}
+	mockValidateSession(t, user)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"userId": user.ID, "exp": time.Now().Add(time.Hour).Unix()})
+	tokenString, _ := token.SignedString(jwtSecret)
+
+	w := serveWithAuthorization(newAuthRouter(), "Bearer "+tokenString)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid token")
+}
+
+func TestOptionalAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/optional", OptionalAuth(), func(c *gin.Context) {
+		_, exists := c.Get("user")
+		c.JSON(http.StatusOK, gin.H{"authenticated": exists})
+	})
+
+	req, _ := http.NewRequest("GET", "/optional", nil)
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"authenticated":false`)
+}
+
+func TestClientIDMiddleware(t *testing.T) {
+	r := gin.New()
+	r.Use(ClientIDMiddleware())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("client_id"))
+	})
+	serve := func(cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "client_id", Value: cookie})
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// kunjungan pertama, client_id sudah tersedia untuk handler
+	w := serve("")
+	clientID, err := uuid.Parse(w.Body.String())
+	assert.NoError(t, err)
+	assert.Contains(t, w.Header().Get("Set-Cookie"), clientID.String())
+
+	// cookie yang sudah ada dipakai ulang
+	assert.Equal(t, clientID.String(), serve(clientID.String()).Body.String())
+
+	// cookie rusak diganti
+	w = serve("not-a-uuid")
+	_, err = uuid.Parse(w.Body.String())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+/** API key scopes, a key can only call the routes of the scopes it was given */
+const (
+	ScopeLinksRead  = "links:read"
+	ScopeLinksWrite = "links:write"
+	ScopeStatsRead  = "stats:read"
+)
+
+var APIKeyScopes = []string{ScopeLinksRead, ScopeLinksWrite, ScopeStatsRead}
+
+/** APIKeyPrefix starts every API key so it can be told apart from a JWT */
+const APIKeyPrefix = "slk_"
+
+/** APIKey is a personal token for scripts, only a hash of the key is stored */
+type APIKey struct {
+	gorm.Model
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	KeyHash    string     `json:"-" gorm:"not null;uniqueIndex"`
+	Scopes     string     `json:"-" gorm:"not null"`
+	ScopeList  []string   `json:"scopes" gorm:"-"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	User       User       `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func IsValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+/** HasScope reports whether the key was given scope */
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range strings.Fields(k.Scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *APIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return
+}
+
+func (k *APIKey) AfterFind(tx *gorm.DB) (err error) {
+	k.ScopeList = strings.Fields(k.Scopes)
+	return
+}
@@ -1,18 +1,52 @@
 package models
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type Link struct {
 	gorm.Model
-	ID         uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	UserID     uuid.UUID
-	URL        string `json:"url" gorm:"unique;not null"`
-	ShortToken string `json:"short_token" gorm:"unique;not null"`
-	Active     bool   `json:"active" gorm:"default:true"`
-	User       User   `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID            uuid.UUID  `gorm:"index:idx_links_user_url,priority:1"`
+	URL               string     `json:"url" gorm:"not null;index:idx_links_user_url,priority:2"`
+	ShortToken        string     `json:"short_token" gorm:"unique;not null"`
+	Active            bool       `json:"active" gorm:"default:true"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	MaxClicks         *int64     `json:"max_clicks"`
+	Clicks            int64      `json:"clicks" gorm:"not null;default:0"`
+	Password          string     `json:"-"`
+	PasswordProtected bool       `json:"password_protected" gorm:"-"`
+	OGTitle           string     `json:"og_title"`
+	OGDescription     string     `json:"og_description"`
+	OGImage           string     `json:"og_image"`
+	OGSiteName        string     `json:"og_site_name"`
+	OGFetchedAt       *time.Time `json:"og_fetched_at"`
+	RedirectType      int        `json:"redirect_type" gorm:"not null;default:0"`
+	ForwardQuery      bool       `json:"forward_query" gorm:"not null;default:false"`
+	ForwardPath       bool       `json:"forward_path" gorm:"not null;default:false"`
+	User              User       `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+/** RedirectTypes are the status codes a link can redirect with, a zero RedirectType uses the server default */
+var RedirectTypes = []int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+/** IsValidRedirectType reports whether code is one of RedirectTypes */
+func IsValidRedirectType(code int) bool {
+	for _, redirectType := range RedirectTypes {
+		if code == redirectType {
+			return true
+		}
+	}
+	return false
 }
 
 func (u *Link) BeforeCreate(tx *gorm.DB) (err error) {
@@ -21,3 +55,8 @@ func (u *Link) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+func (u *Link) AfterFind(tx *gorm.DB) (err error) {
+	u.PasswordProtected = u.Password != ""
+	return
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+/** LinkHistory keeps a previous destination of a link after it was updated */
+type LinkHistory struct {
+	gorm.Model
+	ID     uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	LinkID uuid.UUID `json:"link_id" gorm:"type:uuid;not null;index"`
+	UserID uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
+	URL    string    `json:"url" gorm:"not null"`
+	Link   Link      `json:"-" gorm:"foreignKey:LinkID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (u *LinkHistory) BeforeCreate(tx *gorm.DB) (err error) {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+/** LinkRule sends visitors matching every non-empty condition to URL instead of the link destination */
+type LinkRule struct {
+	gorm.Model
+	ID       uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	LinkID   uuid.UUID `json:"link_id" gorm:"type:uuid;not null;index"`
+	Priority int       `json:"priority" gorm:"not null;default:0"`
+	OS       string    `json:"os"`
+	Device   string    `json:"device"`
+	Language string    `json:"language"`
+	/** Country holds one or more ISO 3166-1 alpha-2 codes separated by commas */
+	Country string `json:"country"`
+	URL     string `json:"url" gorm:"not null"`
+	Link    Link   `json:"-" gorm:"foreignKey:LinkID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (u *LinkRule) BeforeCreate(tx *gorm.DB) (err error) {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return
+}
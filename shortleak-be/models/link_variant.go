@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+/** LinkVariant is one weighted destination of a link split between several URLs */
+type LinkVariant struct {
+	gorm.Model
+	ID     uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	LinkID uuid.UUID `json:"link_id" gorm:"type:uuid;not null;index"`
+	Name   string    `json:"name"`
+	URL    string    `json:"url" gorm:"not null"`
+	Weight int       `json:"weight" gorm:"not null;default:1"`
+	Link   Link      `json:"-" gorm:"foreignKey:LinkID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (u *LinkVariant) BeforeCreate(tx *gorm.DB) (err error) {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return
+}
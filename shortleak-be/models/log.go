@@ -11,7 +11,7 @@ type Log struct {
 	ID     uuid.UUID      `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
 	UserID uuid.UUID      `json:"user_id" gorm:"type:uuid;not null"`
 	Action string         `json:"action" gorm:"not null"`
-	Data   datatypes.JSON `json:"data" gorm:"type:json"`
+	Data   datatypes.JSON `json:"data" gorm:"type:jsonb"`
 }
 
 func (u *Log) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+/** Session is a login on one device, access tokens name it and its refresh token is rotated on every use */
+type Session struct {
+	gorm.Model
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	TokenHash string    `json:"-" gorm:"not null;uniqueIndex"`
+	/** PreviousTokenHash is the refresh token replaced by the last rotation, presenting it again revokes the session */
+	PreviousTokenHash string     `json:"-" gorm:"index"`
+	UserAgent         string     `json:"user_agent"`
+	IPAddress         string     `json:"ip_address"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt         *time.Time `json:"revoked_at"`
+	Current           bool       `json:"current" gorm:"-"`
+	User              User       `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+/** IsActive reports whether the session was neither revoked nor has expired at now */
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+func (s *Session) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
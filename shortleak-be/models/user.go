@@ -1,20 +1,35 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
+/** User roles, admins may manage links of any user */
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	gorm.Model
-	ID       uuid.UUID      `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	FullName string         `json:"fullname" gorm:"column:fullname"`
-	Email    string         `json:"email" gorm:"unique"`
-	Password string         `json:"password"`
-	Active   bool           `json:"active" gorm:"default:true"`
-	Data     datatypes.JSON `json:"data" gorm:"type:json"`
-	Link     []Link         `json:"links" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	ID              uuid.UUID      `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	FullName        string         `json:"fullname" gorm:"column:fullname"`
+	Email           string         `json:"email" gorm:"unique"`
+	Password        string         `json:"password"`
+	Active          bool           `json:"active" gorm:"default:true"`
+	Role            string         `json:"role" gorm:"default:user"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at"`
+	Data            datatypes.JSON `json:"data" gorm:"type:json"`
+	Link            []Link         `json:"links" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+/** IsEmailVerified reports whether the user verified their email address */
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
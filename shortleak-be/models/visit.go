@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+/** Visit is a single redirect of a link, indexed for per-link and time range queries */
+type Visit struct {
+	gorm.Model
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	CreatedAt      time.Time `json:"created_at" gorm:"index:idx_visits_link_created,priority:2"`
+	LinkID         uuid.UUID `json:"link_id" gorm:"type:uuid;not null;index:idx_visits_link_created,priority:1;index:idx_visits_link_client,priority:1;index:idx_visits_link_variant,priority:1"`
+	ClientID       uuid.UUID `json:"client_id" gorm:"type:uuid;not null;index:idx_visits_link_client,priority:2"`
+	Referrer       string    `json:"referrer"`
+	ReferrerDomain string    `json:"referrer_domain"`
+	UserAgent      string    `json:"user_agent"`
+	Browser        string    `json:"browser"`
+	OS             string    `json:"os"`
+	Device         string    `json:"device"`
+	AcceptLanguage string    `json:"accept_language"`
+	Language       string    `json:"language"`
+	Country        string    `json:"country"`
+	Region         string    `json:"region"`
+	City           string    `json:"city"`
+	/** RuleID is the targeting rule that chose the destination, nil when the link URL was used */
+	RuleID *uuid.UUID `json:"rule_id" gorm:"type:uuid"`
+	/** VariantID is the weighted destination the visitor was split to, nil without variants */
+	VariantID *uuid.UUID `json:"variant_id" gorm:"type:uuid;index:idx_visits_link_variant,priority:2"`
+	Link      Link       `json:"-" gorm:"foreignKey:LinkID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (u *Visit) BeforeCreate(tx *gorm.DB) (err error) {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+/** VisitRollupDimensions are the visits columns aggregated into daily dimension rollups */
+var VisitRollupDimensions = []string{"referrer_domain", "browser", "os", "device", "language", "country", "region", "city"}
+
+/** VisitDailyRollup holds the visit counters of a link for one UTC day */
+type VisitDailyRollup struct {
+	gorm.Model
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	LinkID         uuid.UUID `json:"link_id" gorm:"type:uuid;not null;uniqueIndex:idx_visit_daily_rollups_link_day,priority:1"`
+	Day            time.Time `json:"day" gorm:"type:date;not null;uniqueIndex:idx_visit_daily_rollups_link_day,priority:2;index"`
+	Visits         int64     `json:"visits" gorm:"not null;default:0"`
+	UniqueVisitors int64     `json:"unique_visitors" gorm:"not null;default:0"`
+	NewVisitors    int64     `json:"new_visitors" gorm:"not null;default:0"`
+	Link           Link      `json:"-" gorm:"foreignKey:LinkID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (u *VisitDailyRollup) BeforeCreate(tx *gorm.DB) (err error) {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return
+}
+
+/** VisitDailyDimension holds the visits of a link for one value of a dimension on one UTC day */
+type VisitDailyDimension struct {
+	gorm.Model
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	LinkID    uuid.UUID `json:"link_id" gorm:"type:uuid;not null;uniqueIndex:idx_visit_daily_dimensions_key,priority:1"`
+	Dimension string    `json:"dimension" gorm:"not null;uniqueIndex:idx_visit_daily_dimensions_key,priority:2"`
+	Day       time.Time `json:"day" gorm:"type:date;not null;uniqueIndex:idx_visit_daily_dimensions_key,priority:3;index"`
+	Value     string    `json:"value" gorm:"not null;uniqueIndex:idx_visit_daily_dimensions_key,priority:4"`
+	Visits    int64     `json:"visits" gorm:"not null;default:0"`
+	Link      Link      `json:"-" gorm:"foreignKey:LinkID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (u *VisitDailyDimension) BeforeCreate(tx *gorm.DB) (err error) {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"shortleak/database"
+	"shortleak/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func CreateAPIKey(key *models.APIKey) error {
+	result := database.DB.Create(key)
+	return result.Error
+}
+
+func GetAPIKeysByUserID(userID uuid.UUID) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	result := database.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&keys)
+	return keys, result.Error
+}
+
+func GetAPIKey(userID uuid.UUID, keyID uuid.UUID) (*models.APIKey, error) {
+	var key models.APIKey
+	result := database.DB.First(&key, "id = ? AND user_id = ?", keyID, userID)
+	return &key, result.Error
+}
+
+/** GetAPIKeyByHash finds a key with its user for authentication */
+func GetAPIKeyByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	result := database.DB.Preload("User").First(&key, "key_hash = ?", hash)
+	return &key, result.Error
+}
+
+func TouchAPIKey(keyID uuid.UUID, usedAt time.Time) error {
+	result := database.DB.Model(&models.APIKey{}).Where("id = ?", keyID).Update("last_used_at", usedAt)
+	return result.Error
+}
+
+func DeleteAPIKey(key *models.APIKey) error {
+	result := database.DB.Delete(key)
+	return result.Error
+}
@@ -1,13 +1,19 @@
 package repositories
 
 import (
+	"errors"
 	"shortleak/database"
 	"shortleak/models"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
+/** ErrShortTokenTaken is returned when another link got the short token between the check and the insert */
+var ErrShortTokenTaken = errors.New("short token already taken")
+
 func GetAllLinksByUserID(userID uuid.UUID) ([]models.Link, error) {
 	var links []models.Link
 	result := database.DB.Where("user_id = ?", userID).Find(&links)
@@ -16,9 +22,18 @@ func GetAllLinksByUserID(userID uuid.UUID) ([]models.Link, error) {
 
 func CreateLink(link *models.Link) error {
 	result := database.DB.Create(link)
+	if isUniqueViolation(result.Error, "short_token") {
+		return ErrShortTokenTaken
+	}
 	return result.Error
 }
 
+/** isUniqueViolation reports whether err is a Postgres unique violation (23505) of a constraint on column */
+func isUniqueViolation(err error, column string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && strings.Contains(pgErr.ConstraintName, column)
+}
+
 func GetLinkByShortToken(shortToken string) (*models.Link, error) {
 	var link models.Link
 	result := database.DB.Preload("User", func(db *gorm.DB) *gorm.DB {
@@ -31,3 +46,59 @@ func DeleteLink(shortToken string) error {
 	result := database.DB.Where("short_token = ?", shortToken).Delete(&models.Link{})
 	return result.Error
 }
+
+func UpdateLinkActive(shortToken string, active bool) error {
+	result := database.DB.Model(&models.Link{}).Where("short_token = ?", shortToken).Update("active", active)
+	return result.Error
+}
+
+/** ClaimLinkClick counts a click of a link with a click limit, false when the limit was already reached */
+func ClaimLinkClick(linkID uuid.UUID) (bool, error) {
+	result := database.DB.Model(&models.Link{}).
+		Where("id = ? AND max_clicks IS NOT NULL AND clicks < max_clicks", linkID).
+		UpdateColumn("clicks", gorm.Expr("clicks + 1"))
+	return result.RowsAffected == 1, result.Error
+}
+
+/** UpdateLinkOpenGraph stores the cached OpenGraph preview of a link */
+func UpdateLinkOpenGraph(link *models.Link) error {
+	result := database.DB.Model(&models.Link{}).Where("id = ?", link.ID).Updates(map[string]interface{}{
+		"og_title":       link.OGTitle,
+		"og_description": link.OGDescription,
+		"og_image":       link.OGImage,
+		"og_site_name":   link.OGSiteName,
+		"og_fetched_at":  link.OGFetchedAt,
+	})
+	return result.Error
+}
+
+/** UpdateLinkRedirectOptions stores how a link redirects, a map is used so false and zero are written too */
+func UpdateLinkRedirectOptions(link *models.Link) error {
+	result := database.DB.Model(&models.Link{}).Where("id = ?", link.ID).Updates(map[string]interface{}{
+		"redirect_type": link.RedirectType,
+		"forward_query": link.ForwardQuery,
+		"forward_path":  link.ForwardPath,
+	})
+	return result.Error
+}
+
+/** UpdateLinkURL stores the previous destination in history and updates the link URL */
+func UpdateLinkURL(link *models.Link, url string, userID uuid.UUID) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		history := models.LinkHistory{
+			LinkID: link.ID,
+			UserID: userID,
+			URL:    link.URL,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Link{}).Where("id = ?", link.ID).Update("url", url).Error
+	})
+}
+
+func GetLinkHistoryByLinkID(linkID uuid.UUID) ([]models.LinkHistory, error) {
+	var histories []models.LinkHistory
+	result := database.DB.Where("link_id = ?", linkID).Order("created_at desc").Find(&histories)
+	return histories, result.Error
+}
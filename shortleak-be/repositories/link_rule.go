@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"shortleak/database"
+	"shortleak/models"
+
+	"github.com/google/uuid"
+)
+
+/** GetLinkRules returns the rules of a link in evaluation order */
+func GetLinkRules(linkID uuid.UUID) ([]models.LinkRule, error) {
+	var rules []models.LinkRule
+	result := database.DB.Where("link_id = ?", linkID).Order("priority asc, created_at asc").Find(&rules)
+	return rules, result.Error
+}
+
+func GetLinkRule(linkID uuid.UUID, ruleID uuid.UUID) (*models.LinkRule, error) {
+	var rule models.LinkRule
+	result := database.DB.First(&rule, "id = ? AND link_id = ?", ruleID, linkID)
+	return &rule, result.Error
+}
+
+func CreateLinkRule(rule *models.LinkRule) error {
+	result := database.DB.Create(rule)
+	return result.Error
+}
+
+/** UpdateLinkRule stores every field of the rule, a map is used so cleared conditions are written too */
+func UpdateLinkRule(rule *models.LinkRule) error {
+	result := database.DB.Model(&models.LinkRule{}).Where("id = ?", rule.ID).Updates(map[string]interface{}{
+		"priority": rule.Priority,
+		"os":       rule.OS,
+		"device":   rule.Device,
+		"language": rule.Language,
+		"country":  rule.Country,
+		"url":      rule.URL,
+	})
+	return result.Error
+}
+
+func DeleteLinkRule(rule *models.LinkRule) error {
+	result := database.DB.Delete(rule)
+	return result.Error
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"shortleak/database"
+	"shortleak/models"
+
+	"github.com/google/uuid"
+)
+
+/** GetLinkVariants returns the variants of a link in a stable order, the split depends on it */
+func GetLinkVariants(linkID uuid.UUID) ([]models.LinkVariant, error) {
+	var variants []models.LinkVariant
+	result := database.DB.Where("link_id = ?", linkID).Order("created_at asc, id asc").Find(&variants)
+	return variants, result.Error
+}
+
+func GetLinkVariant(linkID uuid.UUID, variantID uuid.UUID) (*models.LinkVariant, error) {
+	var variant models.LinkVariant
+	result := database.DB.First(&variant, "id = ? AND link_id = ?", variantID, linkID)
+	return &variant, result.Error
+}
+
+func CreateLinkVariant(variant *models.LinkVariant) error {
+	result := database.DB.Create(variant)
+	return result.Error
+}
+
+func UpdateLinkVariant(variant *models.LinkVariant) error {
+	result := database.DB.Model(&models.LinkVariant{}).Where("id = ?", variant.ID).Updates(map[string]interface{}{
+		"name":   variant.Name,
+		"url":    variant.URL,
+		"weight": variant.Weight,
+	})
+	return result.Error
+}
+
+func DeleteLinkVariant(variant *models.LinkVariant) error {
+	result := database.DB.Delete(variant)
+	return result.Error
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"errors"
+	"shortleak/database"
+	"shortleak/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/** ErrPasswordResetTokenUsed is returned when another request used the token first */
+var ErrPasswordResetTokenUsed = errors.New("password reset token already used")
+
+func CreatePasswordResetToken(token *models.PasswordResetToken) error {
+	result := database.DB.Create(token)
+	return result.Error
+}
+
+/** GetPasswordResetTokenByHash finds a token with its user */
+func GetPasswordResetTokenByHash(hash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	result := database.DB.Preload("User").First(&token, "token_hash = ?", hash)
+	return &token, result.Error
+}
+
+/** ResetPassword uses the token, sets the new password, voids the other tokens of the user and ends every session */
+func ResetPassword(token *models.PasswordResetToken, password string, now time.Time) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrPasswordResetTokenUsed
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", token.UserID).Update("password", password).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", token.UserID).
+			Update("revoked_at", now).Error
+	})
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"database/sql"
+	"shortleak/database"
+	"shortleak/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/** RollupVisitDay recomputes the daily rollups of every link for the UTC day starting at day */
+func RollupVisitDay(day time.Time) error {
+	start := day.UTC()
+	end := start.AddDate(0, 0, 1)
+	/** Pass the date as text so the session timezone cannot shift it */
+	date := start.Format("2006-01-02")
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("day = ?", date).Delete(&models.VisitDailyRollup{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("day = ?", date).Delete(&models.VisitDailyDimension{}).Error; err != nil {
+			return err
+		}
+
+		/** New visitors are clients seen for the first time on this day, summing them gives all-time uniques */
+		err := tx.Exec(`
+			INSERT INTO visit_daily_rollups (id, created_at, updated_at, link_id, day, visits, unique_visitors, new_visitors)
+			SELECT uuid_generate_v4(), NOW(), NOW(), visits.link_id, CAST(? AS date), COUNT(*), COUNT(DISTINCT visits.client_id),
+				COUNT(DISTINCT visits.client_id) FILTER (WHERE NOT EXISTS (
+					SELECT 1 FROM visits seen
+					WHERE seen.link_id = visits.link_id AND seen.client_id = visits.client_id
+						AND seen.created_at < ? AND seen.deleted_at IS NULL))
+			FROM visits
+			WHERE visits.created_at >= ? AND visits.created_at < ? AND visits.deleted_at IS NULL
+			GROUP BY visits.link_id`, date, start, start, end).Error
+		if err != nil {
+			return err
+		}
+
+		for _, dimension := range models.VisitRollupDimensions {
+			column := clause.Column{Name: dimension}
+			err := tx.Exec(`
+				INSERT INTO visit_daily_dimensions (id, created_at, updated_at, link_id, dimension, day, value, visits)
+				SELECT uuid_generate_v4(), NOW(), NOW(), link_id, ?, CAST(? AS date), ?, COUNT(*)
+				FROM visits
+				WHERE created_at >= ? AND created_at < ? AND deleted_at IS NULL AND ? <> ''
+				GROUP BY link_id, ?`, dimension, date, column, start, end, column, column).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+/** LatestRollupDay returns the most recent rolled up day, ok is false when nothing was rolled up yet */
+func LatestRollupDay() (time.Time, bool, error) {
+	var latest sql.NullTime
+	err := database.DB.Model(&models.VisitDailyRollup{}).Select("MAX(day)").Row().Scan(&latest)
+	return latest.Time.UTC(), latest.Valid, err
+}
+
+/** EarliestVisitTime returns the time of the first recorded visit, ok is false when there are none */
+func EarliestVisitTime() (time.Time, bool, error) {
+	var earliest sql.NullTime
+	err := database.DB.Model(&models.Visit{}).Select("MIN(created_at)").Row().Scan(&earliest)
+	return earliest.Time.UTC(), earliest.Valid, err
+}
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"shortleak/database"
+	"shortleak/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func CreateSession(session *models.Session) error {
+	result := database.DB.Create(session)
+	return result.Error
+}
+
+/** GetSession finds a session with its user for authentication */
+func GetSession(sessionID uuid.UUID) (*models.Session, error) {
+	var session models.Session
+	result := database.DB.Preload("User").First(&session, "id = ?", sessionID)
+	return &session, result.Error
+}
+
+/** GetSessionByTokenHash finds the session a current or previous refresh token belongs to */
+func GetSessionByTokenHash(hash string) (*models.Session, error) {
+	var session models.Session
+	result := database.DB.Preload("User").First(&session, "token_hash = ? OR previous_token_hash = ?", hash, hash)
+	return &session, result.Error
+}
+
+func GetActiveSessionsByUserID(userID uuid.UUID, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	result := database.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at desc").
+		Find(&sessions)
+	return sessions, result.Error
+}
+
+/** RotateSession replaces the refresh token, it reports false when another request rotated it first */
+func RotateSession(session *models.Session, oldHash string) (bool, error) {
+	result := database.DB.Model(&models.Session{}).
+		Where("id = ? AND token_hash = ? AND revoked_at IS NULL", session.ID, oldHash).
+		Updates(map[string]interface{}{
+			"token_hash":          session.TokenHash,
+			"previous_token_hash": oldHash,
+			"last_used_at":        session.LastUsedAt,
+			"expires_at":          session.ExpiresAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func RevokeSession(sessionID uuid.UUID, revokedAt time.Time) error {
+	result := database.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", revokedAt)
+	return result.Error
+}
+
+/** RevokeUserSessions revokes every active session of a user and returns how many were revoked */
+func RevokeUserSessions(userID uuid.UUID, revokedAt time.Time) (int64, error) {
+	result := database.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", revokedAt)
+	return result.RowsAffected, result.Error
+}
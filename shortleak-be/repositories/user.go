@@ -3,6 +3,9 @@ package repositories
 import (
 	"shortleak/database"
 	"shortleak/models"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func GetAllUsers() ([]models.User, error) {
@@ -15,3 +18,23 @@ func CreateUser(user *models.User) error {
 	result := database.DB.Create(user)
 	return result.Error
 }
+
+func GetUserByEmail(email string) (*models.User, error) {
+	var user models.User
+	result := database.DB.First(&user, "email = ?", email)
+	return &user, result.Error
+}
+
+func GetUserByID(userID uuid.UUID) (*models.User, error) {
+	var user models.User
+	result := database.DB.First(&user, "id = ?", userID)
+	return &user, result.Error
+}
+
+/** VerifyUserEmail marks the email verified if it is still the address of the user, it reports false otherwise */
+func VerifyUserEmail(userID uuid.UUID, email string, verifiedAt time.Time) (bool, error) {
+	result := database.DB.Model(&models.User{}).
+		Where("id = ? AND email = ? AND email_verified_at IS NULL", userID, email).
+		Update("email_verified_at", verifiedAt)
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"shortleak/database"
+	"shortleak/models"
+
+	"gorm.io/gorm"
+)
+
+/** CreateVisits inserts visits and their log entries in one transaction */
+func CreateVisits(visits []models.Visit, logs []models.Log) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(visits, 500).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(logs, 500).Error
+	})
+}
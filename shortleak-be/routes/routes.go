@@ -3,16 +3,25 @@ package routes
 import (
 	"shortleak/controllers"
 	"shortleak/middlewares"
+	"shortleak/models"
 
 	"github.com/gin-gonic/gin"
 )
 
 /** SetupRoutes initializes the routes for the application */
 func SetupRoutes(r *gin.Engine) {
+	/** Scopes an API key needs, requests with a session cookie pass them all */
+	linksRead := middlewares.RequireScope(models.ScopeLinksRead)
+	linksWrite := middlewares.RequireScope(models.ScopeLinksWrite)
+	statsRead := middlewares.RequireScope(models.ScopeStatsRead)
+
 	/** Public routes */
 	r.Use(middlewares.ClientIDMiddleware())
 	{
 		r.GET("/:shortToken", controllers.RedirectLink)
+		r.POST("/:shortToken", controllers.RedirectLink)
+		r.GET("/:shortToken/*path", controllers.RedirectLink)
+		r.POST("/:shortToken/*path", controllers.RedirectLink)
 	}
 	routes := r.Group("/api")
 	auth := routes.Group("/auth")
@@ -20,19 +29,58 @@ func SetupRoutes(r *gin.Engine) {
 	{
 		auth.POST("/register", controllers.Register)
 		auth.POST("/login", controllers.Login)
-		auth.POST("/logout", controllers.Logout)
+		auth.POST("/refresh", controllers.Refresh)
+		auth.POST("/logout", middlewares.OptionalAuth(), controllers.Logout)
+		auth.POST("/password-reset/request", controllers.RequestPasswordReset)
+		auth.POST("/password-reset/confirm", controllers.ConfirmPasswordReset)
+		auth.POST("/verify-email", controllers.VerifyEmail)
+		auth.POST("/verify-email/resend", middlewares.AuthRequired(), middlewares.SessionOnly(), controllers.ResendEmailVerification)
+	}
+	session := auth.Group("/sessions")
+	session.Use(middlewares.AuthRequired(), middlewares.SessionOnly())
+	{
+		session.GET("", controllers.GetSessions)
+		session.DELETE("", controllers.LogoutAll)
+		session.DELETE("/:id", controllers.RevokeSession)
 	}
 	link := routes.Group("/links")
 	link.GET("/:shortToken", controllers.GetLinkByShortToken)
 	link.Use(middlewares.AuthRequired())
 	{
-		link.GET("/user", controllers.GetLinksByUserAuth)
-		link.DELETE("/:shortToken", controllers.DeleteLink)
+		link.GET("/user", linksRead, controllers.GetLinksByUserAuth)
+		link.PUT("/:shortToken", linksWrite, controllers.UpdateLink)
+		link.PATCH("/:shortToken", linksWrite, controllers.UpdateLink)
+		link.DELETE("/:shortToken", linksWrite, controllers.DeleteLink)
+		link.GET("/:shortToken/history", linksRead, controllers.GetLinkHistory)
+		link.POST("/:shortToken/open-graph/refresh", linksWrite, controllers.RefreshLinkOpenGraph)
+		link.GET("/:shortToken/rules", linksRead, controllers.GetLinkRules)
+		link.POST("/:shortToken/rules", linksWrite, controllers.CreateLinkRule)
+		link.PUT("/:shortToken/rules/:ruleId", linksWrite, controllers.UpdateLinkRule)
+		link.DELETE("/:shortToken/rules/:ruleId", linksWrite, controllers.DeleteLinkRule)
+		link.GET("/:shortToken/variants", linksRead, controllers.GetLinkVariants)
+		link.POST("/:shortToken/variants", linksWrite, controllers.CreateLinkVariant)
+		link.PUT("/:shortToken/variants/:variantId", linksWrite, controllers.UpdateLinkVariant)
+		link.DELETE("/:shortToken/variants/:variantId", linksWrite, controllers.DeleteLinkVariant)
+		link.PATCH("/:shortToken/activate", linksWrite, controllers.ActivateLink)
+		link.PATCH("/:shortToken/deactivate", linksWrite, controllers.DeactivateLink)
+	}
+	apiKey := routes.Group("/api-keys")
+	apiKey.Use(middlewares.AuthRequired(), middlewares.SessionOnly())
+	{
+		apiKey.GET("", controllers.GetAPIKeys)
+		apiKey.POST("", controllers.CreateAPIKey)
+		apiKey.DELETE("/:id", controllers.RevokeAPIKey)
+	}
+	admin := routes.Group("/admin")
+	admin.Use(middlewares.AuthRequired(), middlewares.SessionOnly())
+	{
+		admin.GET("/visit-queue", controllers.GetVisitQueueStats)
 	}
 	r.Use(middlewares.AuthRequired())
 	{
-		r.POST("/shorten", controllers.CreateLink)
-		r.GET("/stats/:shortToken", controllers.GetLinkStats)
+		r.POST("/shorten", linksWrite, controllers.CreateLink)
+		r.GET("/stats/:shortToken", statsRead, controllers.GetLinkStats)
+		r.GET("/stats/:shortToken/timeseries", statsRead, controllers.GetLinkTimeseries)
 	}
 	// // Protected route
 	// protected := r.Group("/api")
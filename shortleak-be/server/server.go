@@ -1,9 +1,15 @@
 package server
 
 import (
+	"context"
+	"log"
 	"shortleak/config"
+	"shortleak/controllers"
 	"shortleak/database"
 	"shortleak/routes"
+	"shortleak/services"
+	"shortleak/utils"
+	"sync"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -13,8 +19,39 @@ import (
 func SetupRouter() *gin.Engine {
 	cfg := config.LoadConfig()
 	database.ConnectDB(cfg)
+	controllers.AppConfig = cfg
+
+	geoIP, err := utils.OpenGeoIP(cfg.GeoIPDatabasePath)
+	if err != nil {
+		log.Println("⚠️ GeoIP database not loaded, visits will not be geolocated:", err)
+	}
+	controllers.GeoIP = geoIP
+	utils.DefaultOpenGraphFetcher = utils.NewOpenGraphFetcher(utils.OpenGraphOptions{
+		Timeout:      cfg.OpenGraphTimeout,
+		MaxBodyBytes: int64(cfg.OpenGraphMaxBodyBytes),
+		UserAgent:    cfg.OpenGraphUserAgent,
+	})
+	mailer, err := utils.NewMailer(utils.MailerOptions{
+		Driver:   cfg.MailDriver,
+		From:     cfg.MailFrom,
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		FilePath: cfg.MailFilePath,
+		Timeout:  cfg.MailTimeout,
+	})
+	if err != nil {
+		log.Fatalln("❌ Mailer not configured:", err)
+	}
+	controllers.Mailer = mailer
+	controllers.VisitRecorder = services.NewVisitRecorder(cfg.VisitQueueSize, cfg.VisitBatchSize, cfg.VisitFlushInterval, services.SaveVisits)
 
 	r := gin.Default()
+	/** ClientIP keys rate limits, so X-Forwarded-For is only believed from the configured proxies */
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalln("❌ Invalid TRUSTED_PROXIES:", err)
+	}
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"},
@@ -29,3 +66,24 @@ func SetupRouter() *gin.Engine {
 
 	return r
 }
+
+/** StartWorkers runs the background jobs of the server until ctx is done, Wait returns once they stopped */
+func StartWorkers(ctx context.Context) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		services.RunRollupWorker(ctx, controllers.AppConfig.RollupInterval)
+	}()
+	go func() {
+		defer wg.Done()
+		controllers.VisitRecorder.Run(ctx)
+	}()
+	/** Mail still being sent is finished, or timed out, before the server stops */
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		controllers.WaitForMail()
+	}()
+	return &wg
+}
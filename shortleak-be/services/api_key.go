@@ -0,0 +1,104 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"shortleak/models"
+	"shortleak/repositories"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/** ErrInvalidAPIKey is returned for unknown, revoked and expired keys alike */
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+/** apiKeyTouchInterval limits how often the last used time is written for a busy key */
+const apiKeyTouchInterval = time.Minute
+
+var (
+	getAPIKeyByHash = repositories.GetAPIKeyByHash
+	touchAPIKey     = repositories.TouchAPIKey
+)
+
+/** hashToken returns the stored form of a random token, tokens are random enough that a fast hash is safe */
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+/** randomToken returns 32 random bytes encoded for use in headers, cookies and URLs */
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+/** HashAPIKey returns the stored form of a key */
+func HashAPIKey(raw string) string {
+	return hashToken(raw)
+}
+
+/** generateAPIKey returns a new random key with the API key prefix */
+func generateAPIKey() (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	return models.APIKeyPrefix + token, nil
+}
+
+/** CreateAPIKey stores a new key for key.UserID and returns the raw key, it cannot be retrieved later */
+func CreateAPIKey(key *models.APIKey, scopes []string) (string, error) {
+	raw, err := generateAPIKey()
+	if err != nil {
+		return "", err
+	}
+	key.KeyHash = HashAPIKey(raw)
+	key.Prefix = raw[:len(models.APIKeyPrefix)+6]
+	key.Scopes = strings.Join(scopes, " ")
+	key.ScopeList = scopes
+	if err := repositories.CreateAPIKey(key); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+func GetAPIKeys(userID uuid.UUID) ([]models.APIKey, error) {
+	return repositories.GetAPIKeysByUserID(userID)
+}
+
+func GetAPIKey(userID uuid.UUID, keyID uuid.UUID) (*models.APIKey, error) {
+	return repositories.GetAPIKey(userID, keyID)
+}
+
+func RevokeAPIKey(key *models.APIKey) error {
+	return repositories.DeleteAPIKey(key)
+}
+
+/** AuthenticateAPIKey returns the key and its user for a raw key, ErrInvalidAPIKey when it cannot be used */
+func AuthenticateAPIKey(raw string, now time.Time) (*models.APIKey, error) {
+	key, err := getAPIKeyByHash(HashAPIKey(raw))
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, ErrInvalidAPIKey
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		/** Failing to record the use must not fail the request */
+		if err := touchAPIKey(key.ID, now); err != nil {
+			log.Println("⚠️ Failed to update API key last used time:", err)
+		} else {
+			key.LastUsedAt = &now
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"shortleak/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockAPIKeyStore(t *testing.T, key *models.APIKey) *int {
+	touches := 0
+	origGet, origTouch := getAPIKeyByHash, touchAPIKey
+	getAPIKeyByHash = func(hash string) (*models.APIKey, error) {
+		if key == nil || hash != key.KeyHash {
+			return nil, errors.New("record not found")
+		}
+		found := *key
+		return &found, nil
+	}
+	touchAPIKey = func(keyID uuid.UUID, usedAt time.Time) error {
+		touches++
+		return nil
+	}
+	t.Cleanup(func() { getAPIKeyByHash, touchAPIKey = origGet, origTouch })
+	return &touches
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	a, err := generateAPIKey()
+	assert.NoError(t, err)
+	b, _ := generateAPIKey()
+
+	assert.True(t, strings.HasPrefix(a, models.APIKeyPrefix))
+	assert.NotEqual(t, a, b)
+	assert.NotEqual(t, a, HashAPIKey(a))
+	assert.Len(t, HashAPIKey(a), 64)
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	raw := models.APIKeyPrefix + "secret"
+	now := time.Now()
+	touches := mockAPIKeyStore(t, &models.APIKey{ID: uuid.New(), KeyHash: HashAPIKey(raw), Scopes: "links:write"})
+
+	key, err := AuthenticateAPIKey(raw, now)
+	assert.NoError(t, err)
+	assert.True(t, key.HasScope(models.ScopeLinksWrite))
+	assert.False(t, key.HasScope(models.ScopeStatsRead))
+	assert.Equal(t, 1, *touches)
+
+	_, err = AuthenticateAPIKey(models.APIKeyPrefix+"wrong", now)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAuthenticateAPIKeyExpired(t *testing.T) {
+	raw := models.APIKeyPrefix + "secret"
+	expired := time.Now().Add(-time.Hour)
+	mockAPIKeyStore(t, &models.APIKey{ID: uuid.New(), KeyHash: HashAPIKey(raw), ExpiresAt: &expired})
+
+	_, err := AuthenticateAPIKey(raw, time.Now())
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAuthenticateAPIKeyTouchThrottled(t *testing.T) {
+	raw := models.APIKeyPrefix + "secret"
+	lastUsed := time.Now().Add(-10 * time.Second)
+	touches := mockAPIKeyStore(t, &models.APIKey{ID: uuid.New(), KeyHash: HashAPIKey(raw), LastUsedAt: &lastUsed})
+
+	// baru dipakai, last_used_at tidak ditulis ulang
+	_, err := AuthenticateAPIKey(raw, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, *touches)
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/google/uuid"
 )
 
+/** ErrShortTokenTaken is returned by CreateLink when the short token was taken concurrently */
+var ErrShortTokenTaken = repositories.ErrShortTokenTaken
+
 func GetLinksByUserID(userID uuid.UUID) ([]models.Link, error) {
 	return repositories.GetAllLinksByUserID(userID)
 }
@@ -22,3 +25,32 @@ func GetLinkByShortToken(shortToken string) (*models.Link, error) {
 func DeleteLink(shortToken string) error {
 	return repositories.DeleteLink(shortToken)
 }
+
+func SetLinkActive(shortToken string, active bool) error {
+	return repositories.UpdateLinkActive(shortToken, active)
+}
+
+func ClaimLinkClick(linkID uuid.UUID) (bool, error) {
+	return repositories.ClaimLinkClick(linkID)
+}
+
+func UpdateLinkURL(link *models.Link, url string, userID uuid.UUID) error {
+	return repositories.UpdateLinkURL(link, url, userID)
+}
+
+func UpdateLinkRedirectOptions(link *models.Link) error {
+	return repositories.UpdateLinkRedirectOptions(link)
+}
+
+func UpdateLinkOpenGraph(link *models.Link) error {
+	return repositories.UpdateLinkOpenGraph(link)
+}
+
+func GetLinkHistory(linkID uuid.UUID) ([]models.LinkHistory, error) {
+	return repositories.GetLinkHistoryByLinkID(linkID)
+}
+
+/** CanManageLink reports whether the user owns the link or has a role allowed to manage it */
+func CanManageLink(user models.User, link *models.Link) bool {
+	return link.UserID == user.ID || user.Role == models.RoleAdmin
+}
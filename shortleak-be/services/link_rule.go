@@ -0,0 +1,61 @@
+package services
+
+import (
+	"shortleak/models"
+	"shortleak/repositories"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+func GetLinkRules(linkID uuid.UUID) ([]models.LinkRule, error) {
+	return repositories.GetLinkRules(linkID)
+}
+
+func GetLinkRule(linkID uuid.UUID, ruleID uuid.UUID) (*models.LinkRule, error) {
+	return repositories.GetLinkRule(linkID, ruleID)
+}
+
+func CreateLinkRule(rule *models.LinkRule) error {
+	return repositories.CreateLinkRule(rule)
+}
+
+func UpdateLinkRule(rule *models.LinkRule) error {
+	return repositories.UpdateLinkRule(rule)
+}
+
+func DeleteLinkRule(rule *models.LinkRule) error {
+	return repositories.DeleteLinkRule(rule)
+}
+
+/** MatchLinkRule returns the first rule whose conditions all match the visit, nil falls back to the link URL */
+func MatchLinkRule(rules []models.LinkRule, visit models.Visit) *models.LinkRule {
+	for i := range rules {
+		rule := &rules[i]
+		if matchCondition(rule.OS, visit.OS) &&
+			matchCondition(rule.Device, visit.Device) &&
+			matchCondition(rule.Language, visit.Language) &&
+			matchCountry(rule.Country, visit.Country) {
+			return rule
+		}
+	}
+	return nil
+}
+
+/** matchCountry checks the visit country against a comma separated list, an unknown country never matches a list */
+func matchCountry(condition, country string) bool {
+	if condition == "" {
+		return true
+	}
+	for _, code := range strings.Split(condition, ",") {
+		if country != "" && strings.EqualFold(strings.TrimSpace(code), country) {
+			return true
+		}
+	}
+	return false
+}
+
+/** matchCondition treats an empty condition as matching anything, values compare case-insensitively */
+func matchCondition(condition, value string) bool {
+	return condition == "" || strings.EqualFold(condition, value)
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"shortleak/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchLinkRule(t *testing.T) {
+	rules := []models.LinkRule{
+		{ID: uuid.New(), OS: "iOS", URL: "https://apps.apple.com/app/shortleak"},
+		{ID: uuid.New(), OS: "android", Device: "mobile", URL: "https://play.google.com/store/apps/details?id=shortleak"},
+		{ID: uuid.New(), Language: "id", URL: "https://example.com/id"},
+	}
+
+	iphone := models.Visit{OS: "iOS", Device: "mobile", Language: "id"}
+	assert.Equal(t, rules[0].ID, MatchLinkRule(rules, iphone).ID, "rule pertama yang cocok menang")
+
+	androidPhone := models.Visit{OS: "Android", Device: "mobile", Language: "en"}
+	assert.Equal(t, rules[1].ID, MatchLinkRule(rules, androidPhone).ID)
+
+	// tablet android tidak cocok dengan rule mobile
+	androidTablet := models.Visit{OS: "Android", Device: "tablet", Language: "id"}
+	assert.Equal(t, rules[2].ID, MatchLinkRule(rules, androidTablet).ID)
+
+	desktop := models.Visit{OS: "Windows", Device: "desktop", Language: "en"}
+	assert.Nil(t, MatchLinkRule(rules, desktop))
+	assert.Nil(t, MatchLinkRule(nil, desktop))
+}
+
+func TestMatchLinkRuleCountry(t *testing.T) {
+	rules := []models.LinkRule{
+		{ID: uuid.New(), Country: "DE,AT, CH", URL: "https://example.de/promo"},
+		{ID: uuid.New(), Country: "ID", Device: "mobile", URL: "https://m.example.co.id/promo"},
+	}
+
+	assert.Equal(t, rules[0].ID, MatchLinkRule(rules, models.Visit{Country: "CH"}).ID)
+	assert.Equal(t, rules[1].ID, MatchLinkRule(rules, models.Visit{Country: "ID", Device: "mobile"}).ID)
+	assert.Nil(t, MatchLinkRule(rules, models.Visit{Country: "ID", Device: "desktop"}))
+	// negara tidak diketahui tidak cocok dengan rule negara
+	assert.Nil(t, MatchLinkRule(rules, models.Visit{}))
+}
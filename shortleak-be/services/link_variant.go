@@ -0,0 +1,60 @@
+package services
+
+import (
+	"hash/fnv"
+	"shortleak/models"
+	"shortleak/repositories"
+
+	"github.com/google/uuid"
+)
+
+func GetLinkVariants(linkID uuid.UUID) ([]models.LinkVariant, error) {
+	return repositories.GetLinkVariants(linkID)
+}
+
+func GetLinkVariant(linkID uuid.UUID, variantID uuid.UUID) (*models.LinkVariant, error) {
+	return repositories.GetLinkVariant(linkID, variantID)
+}
+
+func CreateLinkVariant(variant *models.LinkVariant) error {
+	return repositories.CreateLinkVariant(variant)
+}
+
+func UpdateLinkVariant(variant *models.LinkVariant) error {
+	return repositories.UpdateLinkVariant(variant)
+}
+
+func DeleteLinkVariant(variant *models.LinkVariant) error {
+	return repositories.DeleteLinkVariant(variant)
+}
+
+/**
+ * PickLinkVariant chooses a variant by weight from a hash of the link and client, so a visitor
+ * keeps getting the same variant as long as the variants and weights do not change.
+ * Nil means the link has no variants and its own URL is used.
+ */
+func PickLinkVariant(variants []models.LinkVariant, linkID uuid.UUID, clientID uuid.UUID) *models.LinkVariant {
+	var total uint64
+	for _, variant := range variants {
+		if variant.Weight > 0 {
+			total += uint64(variant.Weight)
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	h := fnv.New64a()
+	h.Write(linkID[:])
+	h.Write(clientID[:])
+	point := h.Sum64() % total
+	for i := range variants {
+		if variants[i].Weight <= 0 {
+			continue
+		}
+		if point < uint64(variants[i].Weight) {
+			return &variants[i]
+		}
+		point -= uint64(variants[i].Weight)
+	}
+	return nil
+}
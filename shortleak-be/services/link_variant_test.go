@@ -0,0 +1,48 @@
+package services
+
+import (
+	"shortleak/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickLinkVariantSticky(t *testing.T) {
+	linkID := uuid.New()
+	variants := []models.LinkVariant{
+		{ID: uuid.New(), URL: "https://example.com/a", Weight: 1},
+		{ID: uuid.New(), URL: "https://example.com/b", Weight: 1},
+	}
+	clientID := uuid.New()
+
+	first := PickLinkVariant(variants, linkID, clientID)
+	for i := 0; i < 10; i++ {
+		// visitor yang sama selalu dapat variant yang sama
+		assert.Equal(t, first.ID, PickLinkVariant(variants, linkID, clientID).ID)
+	}
+}
+
+func TestPickLinkVariantWeights(t *testing.T) {
+	linkID := uuid.New()
+	variants := []models.LinkVariant{
+		{ID: uuid.New(), URL: "https://example.com/a", Weight: 3},
+		{ID: uuid.New(), URL: "https://example.com/b", Weight: 1},
+		{ID: uuid.New(), URL: "https://example.com/off", Weight: 0},
+	}
+
+	counts := map[uuid.UUID]int{}
+	for i := 0; i < 4000; i++ {
+		counts[PickLinkVariant(variants, linkID, uuid.New()).ID]++
+	}
+
+	// pembagian kira-kira 3:1, weight 0 tidak pernah terpilih
+	assert.InDelta(t, 3000, counts[variants[0].ID], 200)
+	assert.InDelta(t, 1000, counts[variants[1].ID], 200)
+	assert.Zero(t, counts[variants[2].ID])
+}
+
+func TestPickLinkVariantWithoutVariants(t *testing.T) {
+	assert.Nil(t, PickLinkVariant(nil, uuid.New(), uuid.New()))
+	assert.Nil(t, PickLinkVariant([]models.LinkVariant{{Weight: 0}}, uuid.New(), uuid.New()))
+}
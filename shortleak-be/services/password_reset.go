@@ -0,0 +1,60 @@
+package services
+
+import (
+	"errors"
+	"shortleak/models"
+	"shortleak/repositories"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/** ErrInvalidResetToken is returned for unknown, used and expired reset tokens alike */
+var ErrInvalidResetToken = errors.New("invalid password reset token")
+
+var (
+	createPasswordResetToken    = repositories.CreatePasswordResetToken
+	getPasswordResetTokenByHash = repositories.GetPasswordResetTokenByHash
+	resetPassword               = repositories.ResetPassword
+)
+
+/** CreatePasswordResetToken stores a new reset token for the user expiring after ttl and returns the raw token */
+func CreatePasswordResetToken(userID uuid.UUID, ttl time.Duration, now time.Time) (string, error) {
+	raw, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	token := models.PasswordResetToken{
+		UserID:    userID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := createPasswordResetToken(&token); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+/** CheckPasswordResetToken tells whether a raw reset token can still be used, so the new password is only hashed for a usable token */
+func CheckPasswordResetToken(raw string, now time.Time) error {
+	token, err := getPasswordResetTokenByHash(hashToken(raw))
+	if err != nil || !token.IsUsable(now) {
+		return ErrInvalidResetToken
+	}
+	return nil
+}
+
+/** ResetPassword sets the already hashed password of the user a raw reset token belongs to and returns that user */
+func ResetPassword(raw string, hashedPassword string, now time.Time) (*models.User, error) {
+	token, err := getPasswordResetTokenByHash(hashToken(raw))
+	if err != nil || !token.IsUsable(now) {
+		return nil, ErrInvalidResetToken
+	}
+	if err := resetPassword(token, hashedPassword, now); err != nil {
+		if errors.Is(err, repositories.ErrPasswordResetTokenUsed) {
+			return nil, ErrInvalidResetToken
+		}
+		return nil, err
+	}
+	return &token.User, nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"errors"
+	"shortleak/models"
+	"shortleak/repositories"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+/** mockPasswordResetStore keeps tokens in memory and records the passwords set */
+func mockPasswordResetStore(t *testing.T) (*[]models.PasswordResetToken, *[]string) {
+	tokens := []models.PasswordResetToken{}
+	passwords := []string{}
+	origCreate, origGet, origReset := createPasswordResetToken, getPasswordResetTokenByHash, resetPassword
+	createPasswordResetToken = func(token *models.PasswordResetToken) error {
+		token.ID = uuid.New()
+		tokens = append(tokens, *token)
+		return nil
+	}
+	getPasswordResetTokenByHash = func(hash string) (*models.PasswordResetToken, error) {
+		for _, token := range tokens {
+			if token.TokenHash == hash {
+				found := token
+				return &found, nil
+			}
+		}
+		return nil, errors.New("record not found")
+	}
+	resetPassword = func(token *models.PasswordResetToken, password string, now time.Time) error {
+		for i := range tokens {
+			if tokens[i].ID == token.ID {
+				if tokens[i].UsedAt != nil {
+					return repositories.ErrPasswordResetTokenUsed
+				}
+				tokens[i].UsedAt = &now
+			}
+		}
+		passwords = append(passwords, password)
+		return nil
+	}
+	t.Cleanup(func() {
+		createPasswordResetToken, getPasswordResetTokenByHash, resetPassword = origCreate, origGet, origReset
+	})
+	return &tokens, &passwords
+}
+
+func TestResetPasswordSingleUse(t *testing.T) {
+	tokens, passwords := mockPasswordResetStore(t)
+	now := time.Now()
+	userID := uuid.New()
+
+	raw, err := CreatePasswordResetToken(userID, time.Hour, now)
+	assert.NoError(t, err)
+	assert.Len(t, *tokens, 1)
+	assert.NotEqual(t, raw, (*tokens)[0].TokenHash)
+
+	_, err = ResetPassword(raw, "new-hash", now)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new-hash"}, *passwords)
+
+	// token hanya bisa dipakai sekali
+	_, err = ResetPassword(raw, "other-hash", now)
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+	assert.Len(t, *passwords, 1)
+}
+
+func TestResetPasswordExpired(t *testing.T) {
+	_, passwords := mockPasswordResetStore(t)
+	now := time.Now()
+
+	raw, _ := CreatePasswordResetToken(uuid.New(), time.Hour, now)
+
+	_, err := ResetPassword(raw, "new-hash", now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+
+	_, err = ResetPassword("unknown", "new-hash", now)
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+	assert.Empty(t, *passwords)
+}
+
+func TestResetPasswordUsedConcurrently(t *testing.T) {
+	mockPasswordResetStore(t)
+	now := time.Now()
+	raw, _ := CreatePasswordResetToken(uuid.New(), time.Hour, now)
+
+	// request lain sudah memakai token di antara pengecekan dan update
+	resetPassword = func(token *models.PasswordResetToken, password string, now time.Time) error {
+		return repositories.ErrPasswordResetTokenUsed
+	}
+
+	_, err := ResetPassword(raw, "new-hash", now)
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestCheckPasswordResetToken(t *testing.T) {
+	mockPasswordResetStore(t)
+	now := time.Now()
+	raw, _ := CreatePasswordResetToken(uuid.New(), time.Hour, now)
+
+	assert.NoError(t, CheckPasswordResetToken(raw, now))
+	assert.ErrorIs(t, CheckPasswordResetToken(raw, now.Add(2*time.Hour)), ErrInvalidResetToken)
+	assert.ErrorIs(t, CheckPasswordResetToken("unknown", now), ErrInvalidResetToken)
+
+	// token yang sudah dipakai tidak lolos pengecekan
+	_, err := ResetPassword(raw, "new-hash", now)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, CheckPasswordResetToken(raw, now), ErrInvalidResetToken)
+}
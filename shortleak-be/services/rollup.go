@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"log"
+	"shortleak/repositories"
+	"time"
+)
+
+var (
+	rollupVisitDay    = repositories.RollupVisitDay
+	latestRollupDay   = repositories.LatestRollupDay
+	earliestVisitTime = repositories.EarliestVisitTime
+)
+
+/** startOfDay returns the start of the UTC day containing t */
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+/** RollupVisits recomputes the daily rollups of every UTC day from the day of from up to, excluding, the day of to */
+func RollupVisits(from, to time.Time) error {
+	end := startOfDay(to)
+	for day := startOfDay(from); day.Before(end); day = day.AddDate(0, 0, 1) {
+		if err := rollupVisitDay(day); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/** RollupPendingVisits rolls up every completed day since the latest rollup, today is left to the live tail */
+func RollupPendingVisits(now time.Time) error {
+	/** The latest rolled up day is computed again to pick up visits recorded late */
+	from, ok, err := latestRollupDay()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if from, ok, err = earliestVisitTime(); err != nil || !ok {
+			return err
+		}
+	}
+	return RollupVisits(from, now)
+}
+
+/** RunRollupWorker rolls up pending visits now and on every interval until ctx is done, zero disables it */
+func RunRollupWorker(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := RollupPendingVisits(time.Now()); err != nil {
+			log.Println("❌ Visit rollup failed:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockRollupVisitDay(t *testing.T) *[]time.Time {
+	days := []time.Time{}
+	orig := rollupVisitDay
+	rollupVisitDay = func(day time.Time) error {
+		days = append(days, day)
+		return nil
+	}
+	t.Cleanup(func() { rollupVisitDay = orig })
+	return &days
+}
+
+func TestRollupVisitsDays(t *testing.T) {
+	days := mockRollupVisitDay(t)
+
+	err := RollupVisits(time.Date(2025, 10, 1, 15, 0, 0, 0, time.UTC), time.Date(2025, 10, 4, 8, 0, 0, 0, time.UTC))
+
+	// 1, 2, 3 Oktober; hari "to" belum selesai jadi tidak ikut
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 10, 3, 0, 0, 0, 0, time.UTC),
+	}, *days)
+}
+
+func TestRollupPendingVisitsFromLatestRollup(t *testing.T) {
+	days := mockRollupVisitDay(t)
+	origLatest := latestRollupDay
+	latestRollupDay = func() (time.Time, bool, error) {
+		return time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC), true, nil
+	}
+	defer func() { latestRollupDay = origLatest }()
+
+	err := RollupPendingVisits(time.Date(2025, 10, 4, 8, 0, 0, 0, time.UTC))
+
+	// hari terakhir dihitung ulang, hari ini tidak
+	assert.NoError(t, err)
+	assert.Len(t, *days, 2)
+	assert.Equal(t, time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC), (*days)[0])
+}
+
+func TestRollupPendingVisitsFromEarliestVisit(t *testing.T) {
+	days := mockRollupVisitDay(t)
+	origLatest := latestRollupDay
+	latestRollupDay = func() (time.Time, bool, error) { return time.Time{}, false, nil }
+	defer func() { latestRollupDay = origLatest }()
+	origEarliest := earliestVisitTime
+	earliestVisitTime = func() (time.Time, bool, error) {
+		return time.Date(2025, 10, 3, 23, 0, 0, 0, time.UTC), true, nil
+	}
+	defer func() { earliestVisitTime = origEarliest }()
+
+	err := RollupPendingVisits(time.Date(2025, 10, 4, 8, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{time.Date(2025, 10, 3, 0, 0, 0, 0, time.UTC)}, *days)
+}
+
+func TestRollupPendingVisitsNoVisits(t *testing.T) {
+	days := mockRollupVisitDay(t)
+	origLatest := latestRollupDay
+	latestRollupDay = func() (time.Time, bool, error) { return time.Time{}, false, nil }
+	defer func() { latestRollupDay = origLatest }()
+	origEarliest := earliestVisitTime
+	earliestVisitTime = func() (time.Time, bool, error) { return time.Time{}, false, nil }
+	defer func() { earliestVisitTime = origEarliest }()
+
+	err := RollupPendingVisits(time.Now())
+
+	assert.NoError(t, err)
+	assert.Empty(t, *days)
+}
+
+func TestRollupVisitsError(t *testing.T) {
+	orig := rollupVisitDay
+	rollupVisitDay = func(day time.Time) error { return errors.New("rollup failed") }
+	defer func() { rollupVisitDay = orig }()
+
+	err := RollupVisits(time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 10, 3, 0, 0, 0, 0, time.UTC))
+
+	assert.EqualError(t, err, "rollup failed")
+}
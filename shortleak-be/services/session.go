@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"shortleak/models"
+	"shortleak/repositories"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	/** ErrInvalidRefreshToken is returned for unknown, reused, revoked and expired refresh tokens alike */
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	/** ErrInvalidSession is returned when an access token names a session that cannot be used */
+	ErrInvalidSession = errors.New("invalid session")
+)
+
+var (
+	createSession         = repositories.CreateSession
+	getSession            = repositories.GetSession
+	getSessionByTokenHash = repositories.GetSessionByTokenHash
+	rotateSession         = repositories.RotateSession
+	revokeSession         = repositories.RevokeSession
+)
+
+/** CreateSession stores a new session expiring after ttl and returns its raw refresh token */
+func CreateSession(session *models.Session, ttl time.Duration, now time.Time) (string, error) {
+	raw, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	session.TokenHash = hashToken(raw)
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(ttl)
+	if err := createSession(session); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+/** RefreshSession rotates the refresh token of a session and extends it by ttl, a reused token revokes the session */
+func RefreshSession(raw string, ttl time.Duration, now time.Time) (*models.Session, string, error) {
+	hash := hashToken(raw)
+	session, err := getSessionByTokenHash(hash)
+	if err != nil {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if !session.IsActive(now) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if session.User.ID == uuid.Nil {
+		/** The user was deleted, the session can never be used again */
+		if err := revokeSession(session.ID, now); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if session.TokenHash != hash {
+		/** An already rotated token was presented again, it may have been stolen */
+		log.Printf("⚠️ Refresh token reused for session %s, revoking it", session.ID)
+		if err := revokeSession(session.ID, now); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	next, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	session.TokenHash = hashToken(next)
+	session.PreviousTokenHash = hash
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(ttl)
+	rotated, err := rotateSession(session, hash)
+	if err != nil {
+		return nil, "", err
+	}
+	if !rotated {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	return session, next, nil
+}
+
+/** ValidateSession returns the session named by an access token with its user, ErrInvalidSession when it cannot be used */
+func ValidateSession(sessionID uuid.UUID, now time.Time) (*models.Session, error) {
+	session, err := getSession(sessionID)
+	if err != nil || !session.IsActive(now) {
+		return nil, ErrInvalidSession
+	}
+	if session.User.ID == uuid.Nil {
+		/** The user was deleted, the session can never be used again */
+		if err := revokeSession(session.ID, now); err != nil {
+			log.Printf("⚠️ Failed to revoke session %s of a deleted user: %v", session.ID, err)
+		}
+		return nil, ErrInvalidSession
+	}
+	return session, nil
+}
+
+/** FindRefreshSession returns the session of a current or previous refresh token, whether it is active or not */
+func FindRefreshSession(raw string) (*models.Session, error) {
+	return getSessionByTokenHash(hashToken(raw))
+}
+
+func GetSession(sessionID uuid.UUID) (*models.Session, error) {
+	return getSession(sessionID)
+}
+
+func GetActiveSessions(userID uuid.UUID, now time.Time) ([]models.Session, error) {
+	return repositories.GetActiveSessionsByUserID(userID, now)
+}
+
+func RevokeSession(sessionID uuid.UUID, now time.Time) error {
+	return revokeSession(sessionID, now)
+}
+
+func RevokeAllSessions(userID uuid.UUID, now time.Time) (int64, error) {
+	return repositories.RevokeUserSessions(userID, now)
+}
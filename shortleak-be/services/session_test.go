@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"shortleak/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+/** mockSessionStore keeps sessions in memory, rotations and revocations are applied to the stored copy */
+func mockSessionStore(t *testing.T, sessions ...*models.Session) {
+	origCreate, origGet, origGetByHash, origRotate, origRevoke := createSession, getSession, getSessionByTokenHash, rotateSession, revokeSession
+	createSession = func(session *models.Session) error {
+		if session.ID == uuid.Nil {
+			session.ID = uuid.New()
+		}
+		stored := *session
+		sessions = append(sessions, &stored)
+		return nil
+	}
+	find := func(match func(*models.Session) bool) (*models.Session, error) {
+		for _, session := range sessions {
+			if match(session) {
+				found := *session
+				// seperti Preload("User"), user kosong jika UserID tidak ada
+				if found.UserID != uuid.Nil {
+					found.User = models.User{ID: found.UserID}
+				}
+				return &found, nil
+			}
+		}
+		return nil, errors.New("record not found")
+	}
+	getSession = func(sessionID uuid.UUID) (*models.Session, error) {
+		return find(func(s *models.Session) bool { return s.ID == sessionID })
+	}
+	getSessionByTokenHash = func(hash string) (*models.Session, error) {
+		return find(func(s *models.Session) bool { return s.TokenHash == hash || s.PreviousTokenHash == hash })
+	}
+	rotateSession = func(session *models.Session, oldHash string) (bool, error) {
+		for _, stored := range sessions {
+			if stored.ID == session.ID && stored.TokenHash == oldHash && stored.RevokedAt == nil {
+				*stored = *session
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	revokeSession = func(sessionID uuid.UUID, revokedAt time.Time) error {
+		for _, stored := range sessions {
+			if stored.ID == sessionID && stored.RevokedAt == nil {
+				stored.RevokedAt = &revokedAt
+			}
+		}
+		return nil
+	}
+	t.Cleanup(func() {
+		createSession, getSession, getSessionByTokenHash, rotateSession, revokeSession = origCreate, origGet, origGetByHash, origRotate, origRevoke
+	})
+}
+
+func TestCreateSession(t *testing.T) {
+	mockSessionStore(t)
+	now := time.Now()
+	session := models.Session{UserID: uuid.New()}
+
+	raw, err := CreateSession(&session, time.Hour, now)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, raw)
+	assert.NotEqual(t, raw, session.TokenHash)
+	assert.Equal(t, now.Add(time.Hour), session.ExpiresAt)
+
+	found, err := FindRefreshSession(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, session.ID, found.ID)
+}
+
+func TestRefreshSessionRotatesToken(t *testing.T) {
+	mockSessionStore(t)
+	now := time.Now()
+	session := models.Session{UserID: uuid.New()}
+	raw, _ := CreateSession(&session, time.Hour, now)
+
+	later := now.Add(30 * time.Minute)
+	refreshed, next, err := RefreshSession(raw, time.Hour, later)
+
+	assert.NoError(t, err)
+	assert.Equal(t, session.ID, refreshed.ID)
+	assert.NotEqual(t, raw, next)
+	assert.Equal(t, later.Add(time.Hour), refreshed.ExpiresAt)
+
+	// token baru bisa dipakai lagi
+	_, _, err = RefreshSession(next, time.Hour, later)
+	assert.NoError(t, err)
+}
+
+func TestRefreshSessionReuseRevokesSession(t *testing.T) {
+	mockSessionStore(t)
+	now := time.Now()
+	session := models.Session{UserID: uuid.New()}
+	raw, _ := CreateSession(&session, time.Hour, now)
+	_, next, err := RefreshSession(raw, time.Hour, now)
+	assert.NoError(t, err)
+
+	// token lama dipakai lagi, session dicabut
+	_, _, err = RefreshSession(raw, time.Hour, now)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// token terbaru juga tidak berlaku lagi
+	_, _, err = RefreshSession(next, time.Hour, now)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = ValidateSession(session.ID, now)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+}
+
+func TestRefreshSessionExpired(t *testing.T) {
+	mockSessionStore(t)
+	now := time.Now()
+	session := models.Session{UserID: uuid.New()}
+	raw, _ := CreateSession(&session, time.Hour, now)
+
+	_, _, err := RefreshSession(raw, time.Hour, now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	_, _, err = RefreshSession("unknown", time.Hour, now)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestValidateSession(t *testing.T) {
+	mockSessionStore(t)
+	now := time.Now()
+	session := models.Session{UserID: uuid.New()}
+	CreateSession(&session, time.Hour, now)
+
+	found, err := ValidateSession(session.ID, now)
+	assert.NoError(t, err)
+	assert.Equal(t, session.UserID, found.UserID)
+
+	assert.NoError(t, RevokeSession(session.ID, now))
+	_, err = ValidateSession(session.ID, now)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+
+	_, err = ValidateSession(uuid.New(), now)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+}
+
+func TestValidateSessionDeletedUser(t *testing.T) {
+	mockSessionStore(t)
+	now := time.Now()
+	// user sudah dihapus, preload User kosong
+	session := models.Session{}
+	raw, _ := CreateSession(&session, time.Hour, now)
+
+	_, err := ValidateSession(session.ID, now)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+
+	// session ikut dicabut
+	found, _ := FindRefreshSession(raw)
+	assert.NotNil(t, found.RevokedAt)
+}
+
+func TestRefreshSessionDeletedUser(t *testing.T) {
+	mockSessionStore(t)
+	now := time.Now()
+	session := models.Session{}
+	raw, _ := CreateSession(&session, time.Hour, now)
+
+	_, _, err := RefreshSession(raw, time.Hour, now)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	found, _ := FindRefreshSession(raw)
+	assert.NotNil(t, found.RevokedAt)
+}
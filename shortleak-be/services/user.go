@@ -3,6 +3,9 @@ package services
 import (
 	"shortleak/models"
 	"shortleak/repositories"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func GetUsers() ([]models.User, error) {
@@ -12,3 +15,15 @@ func GetUsers() ([]models.User, error) {
 func AddUser(user *models.User) error {
 	return repositories.CreateUser(user)
 }
+
+func GetUserByEmail(email string) (*models.User, error) {
+	return repositories.GetUserByEmail(email)
+}
+
+func GetUser(userID uuid.UUID) (*models.User, error) {
+	return repositories.GetUserByID(userID)
+}
+
+func VerifyUserEmail(userID uuid.UUID, email string, now time.Time) (bool, error) {
+	return repositories.VerifyUserEmail(userID, email, now)
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"encoding/json"
+	"shortleak/models"
+	"shortleak/repositories"
+
+	"gorm.io/datatypes"
+)
+
+var createVisits = repositories.CreateVisits
+
+/** SaveVisits writes a batch of visits with their "visit-link" logs */
+func SaveVisits(events []VisitEvent) error {
+	visits := make([]models.Visit, 0, len(events))
+	logs := make([]models.Log, 0, len(events))
+	for _, event := range events {
+		b, _ := json.Marshal(map[string]interface{}{
+			"shortToken": event.ShortToken,
+			"visitId":    event.Visit.ID,
+		})
+		entry := models.Log{
+			UserID: event.Visit.ClientID,
+			Action: "visit-link",
+			Data:   datatypes.JSON(b),
+		}
+		entry.CreatedAt = event.Visit.CreatedAt
+		visits = append(visits, event.Visit)
+		logs = append(logs, entry)
+	}
+	return createVisits(visits, logs)
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"log"
+	"shortleak/models"
+	"sync/atomic"
+	"time"
+)
+
+/** VisitEvent is a redirect waiting to be written */
+type VisitEvent struct {
+	Visit      models.Visit
+	ShortToken string
+}
+
+/** VisitRecorderStats are the queue counters exposed for monitoring */
+type VisitRecorderStats struct {
+	Queued   int    `json:"queued"`
+	Capacity int    `json:"capacity"`
+	Enqueued uint64 `json:"enqueued"`
+	Dropped  uint64 `json:"dropped"`
+	Flushed  uint64 `json:"flushed"`
+	Failed   uint64 `json:"failed"`
+	Batches  uint64 `json:"batches"`
+}
+
+/** VisitRecorder buffers visits in a bounded queue and writes them in batches from a background worker */
+type VisitRecorder struct {
+	queue         chan VisitEvent
+	batchSize     int
+	flushInterval time.Duration
+	flush         func([]VisitEvent) error
+	closed        atomic.Bool
+	done          chan struct{}
+
+	enqueued atomic.Uint64
+	dropped  atomic.Uint64
+	flushed  atomic.Uint64
+	failed   atomic.Uint64
+	batches  atomic.Uint64
+}
+
+/** Defaults used when NewVisitRecorder gets a zero or negative setting */
+const (
+	defaultVisitQueueSize     = 10000
+	defaultVisitBatchSize     = 500
+	defaultVisitFlushInterval = time.Second
+)
+
+/** NewVisitRecorder creates a recorder, settings that are not positive fall back to the defaults */
+func NewVisitRecorder(capacity, batchSize int, flushInterval time.Duration, flush func([]VisitEvent) error) *VisitRecorder {
+	if capacity <= 0 {
+		capacity = defaultVisitQueueSize
+	}
+	if batchSize <= 0 {
+		batchSize = defaultVisitBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = defaultVisitFlushInterval
+	}
+	return &VisitRecorder{
+		queue:         make(chan VisitEvent, capacity),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		flush:         flush,
+		done:          make(chan struct{}),
+	}
+}
+
+/** Record queues a visit without blocking, it is dropped and counted when the queue is full or closed */
+func (r *VisitRecorder) Record(event VisitEvent) bool {
+	if r == nil || r.closed.Load() {
+		if r != nil {
+			r.dropped.Add(1)
+		}
+		return false
+	}
+	select {
+	case r.queue <- event:
+		r.enqueued.Add(1)
+		return true
+	default:
+		r.dropped.Add(1)
+		return false
+	}
+}
+
+/** Run writes queued visits until ctx is done, then flushes what is left and closes Done */
+func (r *VisitRecorder) Run(ctx context.Context) {
+	defer close(r.done)
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]VisitEvent, 0, r.batchSize)
+	var reportedDrops uint64
+	for {
+		select {
+		case event := <-r.queue:
+			batch = append(batch, event)
+			if len(batch) >= r.batchSize {
+				batch = r.write(batch)
+			}
+		case <-ticker.C:
+			batch = r.write(batch)
+			if dropped := r.dropped.Load(); dropped > reportedDrops {
+				log.Printf("⚠️ Visit queue full, dropped %d visits", dropped-reportedDrops)
+				reportedDrops = dropped
+			}
+		case <-ctx.Done():
+			r.closed.Store(true)
+			for {
+				select {
+				case event := <-r.queue:
+					batch = append(batch, event)
+					if len(batch) >= r.batchSize {
+						batch = r.write(batch)
+					}
+				default:
+					r.write(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+/** write flushes a batch, on failure every visit is retried alone so one bad row cannot lose the batch */
+func (r *VisitRecorder) write(batch []VisitEvent) []VisitEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+	r.batches.Add(1)
+	if err := r.flush(batch); err != nil {
+		log.Println("❌ Visit batch failed, retrying one by one:", err)
+		for _, event := range batch {
+			if err := r.flush([]VisitEvent{event}); err != nil {
+				log.Println("❌ Visit dropped:", err)
+				r.failed.Add(1)
+				continue
+			}
+			r.flushed.Add(1)
+		}
+	} else {
+		r.flushed.Add(uint64(len(batch)))
+	}
+	return batch[:0]
+}
+
+/** Done is closed once the worker has flushed the queue after shutdown */
+func (r *VisitRecorder) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *VisitRecorder) Stats() VisitRecorderStats {
+	if r == nil {
+		return VisitRecorderStats{}
+	}
+	return VisitRecorderStats{
+		Queued:   len(r.queue),
+		Capacity: cap(r.queue),
+		Enqueued: r.enqueued.Load(),
+		Dropped:  r.dropped.Load(),
+		Flushed:  r.flushed.Load(),
+		Failed:   r.failed.Load(),
+		Batches:  r.batches.Load(),
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"shortleak/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type batchCollector struct {
+	mu      sync.Mutex
+	batches [][]VisitEvent
+	err     func(batch []VisitEvent) error
+}
+
+func (b *batchCollector) flush(batch []VisitEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		if err := b.err(batch); err != nil {
+			return err
+		}
+	}
+	b.batches = append(b.batches, append([]VisitEvent(nil), batch...))
+	return nil
+}
+
+func visitEvent(token string) VisitEvent {
+	return VisitEvent{Visit: models.Visit{ID: uuid.New()}, ShortToken: token}
+}
+
+func TestVisitRecorderDropsWhenFull(t *testing.T) {
+	collector := &batchCollector{}
+	recorder := NewVisitRecorder(2, 10, time.Hour, collector.flush)
+
+	assert.True(t, recorder.Record(visitEvent("a")))
+	assert.True(t, recorder.Record(visitEvent("b")))
+	// antrian penuh, visit ketiga dibuang
+	assert.False(t, recorder.Record(visitEvent("c")))
+
+	stats := recorder.Stats()
+	assert.Equal(t, 2, stats.Queued)
+	assert.Equal(t, uint64(2), stats.Enqueued)
+	assert.Equal(t, uint64(1), stats.Dropped)
+}
+
+func TestNewVisitRecorderDefaults(t *testing.T) {
+	collector := &batchCollector{}
+	recorder := NewVisitRecorder(0, -1, 0, collector.flush)
+
+	assert.Equal(t, defaultVisitQueueSize, recorder.Stats().Capacity)
+	assert.Equal(t, defaultVisitBatchSize, recorder.batchSize)
+	assert.Equal(t, defaultVisitFlushInterval, recorder.flushInterval)
+
+	// interval nol tidak membuat ticker panic
+	ctx, cancel := context.WithCancel(context.Background())
+	go recorder.Run(ctx)
+	recorder.Record(visitEvent("a"))
+	cancel()
+	<-recorder.Done()
+
+	assert.Equal(t, uint64(1), recorder.Stats().Flushed)
+}
+
+func TestVisitRecorderFlushesBatchesAndOnShutdown(t *testing.T) {
+	collector := &batchCollector{}
+	recorder := NewVisitRecorder(10, 2, time.Hour, collector.flush)
+	for _, token := range []string{"a", "b", "c"} {
+		recorder.Record(visitEvent(token))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go recorder.Run(ctx)
+	cancel()
+	<-recorder.Done()
+
+	// batch penuh (2) lalu sisa (1) saat shutdown
+	assert.Len(t, collector.batches, 2)
+	assert.Len(t, collector.batches[0], 2)
+	assert.Equal(t, "c", collector.batches[1][0].ShortToken)
+	assert.Equal(t, uint64(3), recorder.Stats().Flushed)
+
+	// setelah shutdown visit tidak diterima lagi
+	assert.False(t, recorder.Record(visitEvent("d")))
+}
+
+func TestVisitRecorderFlushesOnInterval(t *testing.T) {
+	collector := &batchCollector{}
+	recorder := NewVisitRecorder(10, 100, 10*time.Millisecond, collector.flush)
+	ctx, cancel := context.WithCancel(context.Background())
+	go recorder.Run(ctx)
+	defer func() {
+		cancel()
+		<-recorder.Done()
+	}()
+
+	recorder.Record(visitEvent("a"))
+
+	assert.Eventually(t, func() bool { return recorder.Stats().Flushed == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestVisitRecorderRetriesFailedBatchOneByOne(t *testing.T) {
+	collector := &batchCollector{err: func(batch []VisitEvent) error {
+		for _, event := range batch {
+			if event.ShortToken == "gone" {
+				return errors.New("foreign key violation")
+			}
+		}
+		return nil
+	}}
+	recorder := NewVisitRecorder(10, 10, time.Hour, collector.flush)
+	recorder.Record(visitEvent("a"))
+	recorder.Record(visitEvent("gone"))
+	recorder.Record(visitEvent("b"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	recorder.Run(ctx)
+
+	stats := recorder.Stats()
+	assert.Equal(t, uint64(2), stats.Flushed)
+	assert.Equal(t, uint64(1), stats.Failed)
+}
+
+func TestNilVisitRecorder(t *testing.T) {
+	var recorder *VisitRecorder
+
+	assert.False(t, recorder.Record(visitEvent("a")))
+	assert.Equal(t, VisitRecorderStats{}, recorder.Stats())
+}
+
+func TestSaveVisitsWritesLogs(t *testing.T) {
+	var savedVisits []models.Visit
+	var savedLogs []models.Log
+	origCreate := createVisits
+	createVisits = func(visits []models.Visit, logs []models.Log) error {
+		savedVisits, savedLogs = visits, logs
+		return nil
+	}
+	defer func() { createVisits = origCreate }()
+
+	clicked := time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC)
+	clientID := uuid.New()
+	events := []VisitEvent{
+		{Visit: models.Visit{ID: uuid.New(), ClientID: clientID, CreatedAt: clicked}, ShortToken: "abcde"},
+		{Visit: models.Visit{ID: uuid.New(), ClientID: clientID, CreatedAt: clicked}, ShortToken: "fghij"},
+	}
+
+	err := SaveVisits(events)
+
+	assert.NoError(t, err)
+	assert.Len(t, savedVisits, 2)
+	assert.Len(t, savedLogs, 2)
+	assert.Equal(t, "visit-link", savedLogs[0].Action)
+	assert.Equal(t, clientID, savedLogs[0].UserID)
+	// waktu log mengikuti waktu klik, bukan waktu flush
+	assert.Equal(t, clicked, savedLogs[0].CreatedAt)
+
+	var payload map[string]interface{}
+	_ = json.Unmarshal(savedLogs[1].Data, &payload)
+	assert.Equal(t, "fghij", payload["shortToken"])
+	assert.Equal(t, events[1].Visit.ID.String(), payload["visitId"])
+}
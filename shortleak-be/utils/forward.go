@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"net/url"
+	"path"
+)
+
+/** ForwardURL appends extraPath to the path of destination and merges query into its query string, parameters already in destination win */
+func ForwardURL(destination, extraPath string, query url.Values) (string, error) {
+	target, err := url.Parse(destination)
+	if err != nil {
+		return "", err
+	}
+	/** Clean as a rooted path so ".." cannot climb above the destination path */
+	if extraPath = path.Clean("/" + extraPath); extraPath != "/" {
+		target = target.JoinPath(extraPath)
+	}
+	if len(query) > 0 {
+		merged := target.Query()
+		for key, values := range query {
+			if _, exists := merged[key]; !exists {
+				merged[key] = values
+			}
+		}
+		target.RawQuery = merged.Encode()
+	}
+	return target.String(), nil
+}
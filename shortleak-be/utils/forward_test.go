@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForwardURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		destination string
+		extraPath   string
+		query       string
+		expected    string
+	}{
+		{"unchanged", "https://example.com/docs?a=1", "", "", "https://example.com/docs?a=1"},
+		{"path", "https://example.com/docs", "/guide/intro", "", "https://example.com/docs/guide/intro"},
+		{"path on root", "https://example.com", "/pricing", "", "https://example.com/pricing"},
+		{"path keeps query", "https://example.com/docs?a=1", "/x", "", "https://example.com/docs/x?a=1"},
+		{"path traversal", "https://example.com/docs", "/../../admin", "", "https://example.com/docs/admin"},
+		{"query", "https://example.com/", "", "utm_source=mail&utm_medium=email", "https://example.com/?utm_medium=email&utm_source=mail"},
+		// parameter dari destinasi yang menang
+		{"destination wins", "https://example.com/?ref=partner", "", "ref=spam&utm_source=x", "https://example.com/?ref=partner&utm_source=x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			result, err := ForwardURL(tt.destination, tt.extraPath, query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestForwardURLInvalidDestination(t *testing.T) {
+	_, err := ForwardURL("http://[::1", "/x", nil)
+	assert.Error(t, err)
+}
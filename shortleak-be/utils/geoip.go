@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"net"
+	"sync"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+/** GeoLocation is the country, region and city resolved for an IP address */
+type GeoLocation struct {
+	Country string `json:"country"`
+	Region  string `json:"region"`
+	City    string `json:"city"`
+}
+
+/** geoRecord is the subset of a GeoLite2/GeoIP2 City record we read */
+type geoRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+/** GeoIP resolves IP addresses against a local MaxMind MMDB file, a nil *GeoIP resolves every address to an empty location */
+type GeoIP struct {
+	mu     sync.RWMutex
+	reader *maxminddb.Reader
+}
+
+/** OpenGeoIP opens the MMDB file at path, an empty path returns a nil *GeoIP without error */
+func OpenGeoIP(path string) (*GeoIP, error) {
+	if path == "" {
+		return nil, nil
+	}
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &GeoIP{reader: reader}, nil
+}
+
+/** Lookup returns the location of ip, unknown, private or unparsable addresses and lookup errors yield an empty location */
+func (g *GeoIP) Lookup(ip string) GeoLocation {
+	if g == nil {
+		return GeoLocation{}
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return GeoLocation{}
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.reader == nil {
+		return GeoLocation{}
+	}
+
+	var record geoRecord
+	if err := g.reader.Lookup(parsed, &record); err != nil {
+		return GeoLocation{}
+	}
+	location := GeoLocation{
+		Country: record.Country.ISOCode,
+		City:    record.City.Names["en"],
+	}
+	if len(record.Subdivisions) > 0 {
+		location.Region = record.Subdivisions[0].Names["en"]
+	}
+	return location
+}
+
+/** Close releases the underlying database file */
+func (g *GeoIP) Close() error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.reader == nil {
+		return nil
+	}
+	err := g.reader.Close()
+	g.reader = nil
+	return err
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenGeoIPWithoutPath(t *testing.T) {
+	geoIP, err := OpenGeoIP("")
+
+	assert.NoError(t, err)
+	assert.Nil(t, geoIP)
+}
+
+func TestOpenGeoIPMissingFile(t *testing.T) {
+	geoIP, err := OpenGeoIP("/nonexistent/GeoLite2-City.mmdb")
+
+	assert.Error(t, err)
+	assert.Nil(t, geoIP)
+}
+
+func TestGeoIPLookupDisabled(t *testing.T) {
+	// tanpa database, lookup harus tetap jalan dan hasilnya kosong
+	var geoIP *GeoIP
+
+	assert.Equal(t, GeoLocation{}, geoIP.Lookup("8.8.8.8"))
+	assert.Equal(t, GeoLocation{}, geoIP.Lookup("not-an-ip"))
+	assert.NoError(t, geoIP.Close())
+}
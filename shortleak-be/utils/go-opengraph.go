@@ -1,21 +1,198 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"syscall"
+	"time"
 
 	"github.com/dyatlov/go-opengraph/opengraph"
 )
 
-func GetOpenGraphData(url string) (opengraph.OpenGraph, error) {
-	resp, err := http.Get(url)
+var (
+	/** ErrBlockedAddress is returned when a fetch would connect to a private, loopback or link-local address */
+	ErrBlockedAddress = errors.New("destination address is not allowed")
+	/** ErrUnsupportedContentType is returned when the destination does not serve HTML */
+	ErrUnsupportedContentType = errors.New("destination is not an HTML page")
+)
+
+/** OpenGraphOptions configures how destinations are fetched for OpenGraph data */
+type OpenGraphOptions struct {
+	Timeout      time.Duration
+	MaxBodyBytes int64
+	MaxRedirects int
+	UserAgent    string
+}
+
+/** DefaultOpenGraphOptions are used when no options are configured */
+var DefaultOpenGraphOptions = OpenGraphOptions{
+	Timeout:      5 * time.Second,
+	MaxBodyBytes: 1 << 20,
+	MaxRedirects: 5,
+	UserAgent:    "ShortleakBot/1.0 (+link preview)",
+}
+
+/** OpenGraphFetcher fetches OpenGraph data while refusing to connect to internal addresses */
+type OpenGraphFetcher struct {
+	options   OpenGraphOptions
+	client    *http.Client
+	blockedIP func(ip net.IP) bool
+}
+
+/** DefaultOpenGraphFetcher is used by GetOpenGraphData, replace it to change the options */
+var DefaultOpenGraphFetcher = NewOpenGraphFetcher(DefaultOpenGraphOptions)
+
+/** NewOpenGraphFetcher returns a fetcher with the given options, zero values fall back to the defaults */
+func NewOpenGraphFetcher(options OpenGraphOptions) *OpenGraphFetcher {
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultOpenGraphOptions.Timeout
+	}
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = DefaultOpenGraphOptions.MaxBodyBytes
+	}
+	if options.MaxRedirects <= 0 {
+		options.MaxRedirects = DefaultOpenGraphOptions.MaxRedirects
+	}
+	if options.UserAgent == "" {
+		options.UserAgent = DefaultOpenGraphOptions.UserAgent
+	}
+
+	f := &OpenGraphFetcher{options: options, blockedIP: IsBlockedIP}
+	/** The address is checked on every connection, after DNS resolution and for every redirect */
+	dialer := &net.Dialer{
+		Timeout: options.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || f.blockedIP(ip) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+	f.client = &http.Client{
+		Timeout: options.Timeout,
+		Transport: &http.Transport{
+			/** No proxy, it would make the dialer check the proxy instead of the destination */
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   options.Timeout,
+			ResponseHeaderTimeout: options.Timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > options.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", options.MaxRedirects)
+			}
+			return checkFetchURL(req.URL)
+		},
+	}
+	return f
+}
+
+/** Fetch downloads the page at rawURL and parses its OpenGraph data */
+func (f *OpenGraphFetcher) Fetch(rawURL string) (opengraph.OpenGraph, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return opengraph.OpenGraph{}, err
+	}
+	if err := checkFetchURL(target); err != nil {
+		return opengraph.OpenGraph{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.options.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return opengraph.OpenGraph{}, err
+	}
+	req.Header.Set("User-Agent", f.options.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
 	if err != nil {
 		return opengraph.OpenGraph{}, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return opengraph.OpenGraph{}, fmt.Errorf("destination responded with status %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return opengraph.OpenGraph{}, ErrUnsupportedContentType
+	}
+
+	/** OpenGraph tags live in the head, a truncated body is still parsed */
 	og := opengraph.NewOpenGraph()
-	if err := og.ProcessHTML(resp.Body); err != nil {
+	if err := og.ProcessHTML(io.LimitReader(resp.Body, f.options.MaxBodyBytes)); err != nil {
 		return opengraph.OpenGraph{}, err
 	}
 	return *og, nil
 }
+
+/** GetOpenGraphData fetches the OpenGraph data of a URL with DefaultOpenGraphFetcher */
+func GetOpenGraphData(url string) (opengraph.OpenGraph, error) {
+	return DefaultOpenGraphFetcher.Fetch(url)
+}
+
+/** checkFetchURL only allows plain http(s) URLs */
+func checkFetchURL(target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", target.Scheme)
+	}
+	if target.Hostname() == "" {
+		return errors.New("URL has no host")
+	}
+	return nil
+}
+
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",      /** "this" network */
+	"100.64.0.0/10",  /** carrier-grade NAT */
+	"192.0.0.0/24",   /** IETF protocol assignments */
+	"198.18.0.0/15",  /** benchmarking */
+	"240.0.0.0/4",    /** reserved */
+	"64:ff9b::/96",   /** NAT64, can map to internal IPv4 */
+	"64:ff9b:1::/48", /** local-use NAT64 */
+	"2001:db8::/32",  /** documentation */
+)
+
+/** IsBlockedIP reports whether ip is loopback, private, link-local, multicast, unspecified or otherwise internal */
+func IsBlockedIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fetcher untuk test, httptest jalan di loopback jadi loopback diizinkan
+func newTestFetcher(options OpenGraphOptions) *OpenGraphFetcher {
+	f := NewOpenGraphFetcher(options)
+	f.blockedIP = func(ip net.IP) bool {
+		return !ip.IsLoopback() && IsBlockedIP(ip)
+	}
+	return f
+}
+
+func TestIsBlockedIP(t *testing.T) {
+	blocked := []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1", "224.0.0.1"}
+	for _, ip := range blocked {
+		assert.True(t, IsBlockedIP(net.ParseIP(ip)), ip)
+	}
+	allowed := []string{"8.8.8.8", "93.184.216.34", "2606:4700:4700::1111"}
+	for _, ip := range allowed {
+		assert.False(t, IsBlockedIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestOpenGraphFetcherSuccess(t *testing.T) {
+	var userAgent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><meta property="og:title" content="Example Title"></head></html>`))
+	}))
+	defer srv.Close()
+
+	og, err := newTestFetcher(OpenGraphOptions{UserAgent: "TestBot/1.0"}).Fetch(srv.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Example Title", og.Title)
+	assert.Equal(t, "TestBot/1.0", userAgent)
+}
+
+func TestOpenGraphFetcherBlocksLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not reach the server")
+	}))
+	defer srv.Close()
+
+	_, err := NewOpenGraphFetcher(OpenGraphOptions{}).Fetch(srv.URL)
+
+	assert.True(t, errors.Is(err, ErrBlockedAddress), err)
+}
+
+func TestOpenGraphFetcherBlocksRedirectToInternal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://10.0.0.1/latest/meta-data/", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	_, err := newTestFetcher(OpenGraphOptions{}).Fetch(srv.URL)
+
+	assert.True(t, errors.Is(err, ErrBlockedAddress), err)
+}
+
+func TestOpenGraphFetcherRejectsNonHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	_, err := newTestFetcher(OpenGraphOptions{}).Fetch(srv.URL)
+
+	assert.ErrorIs(t, err, ErrUnsupportedContentType)
+}
+
+func TestOpenGraphFetcherLimitsBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		// tag og ada setelah batas body, jadi tidak terbaca
+		w.Write([]byte("<html><head>" + strings.Repeat(" ", 2048) + `<meta property="og:title" content="Too Far"></head></html>`))
+	}))
+	defer srv.Close()
+
+	og, err := newTestFetcher(OpenGraphOptions{MaxBodyBytes: 1024}).Fetch(srv.URL)
+
+	assert.NoError(t, err)
+	assert.Empty(t, og.Title)
+}
+
+func TestOpenGraphFetcherTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	_, err := newTestFetcher(OpenGraphOptions{Timeout: 50 * time.Millisecond}).Fetch(srv.URL)
+
+	assert.Error(t, err)
+}
+
+func TestOpenGraphFetcherRejectsScheme(t *testing.T) {
+	_, err := NewOpenGraphFetcher(OpenGraphOptions{}).Fetch("file:///etc/passwd")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported URL scheme")
+}
@@ -2,7 +2,9 @@ package utils
 
 import (
 	"math/rand"
+	"net/url"
 	"os"
+	"strings"
 )
 
 // GetEnv returns the value of the environment variable named by the key.
@@ -35,3 +37,27 @@ var GenerateRandomString = func(n int) string {
 	}
 	return string(s)
 }
+
+// ReferrerDomain returns the host of a Referer header without a leading "www.".
+// It returns an empty string for a missing or unparsable referrer.
+func ReferrerDomain(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// PrimaryLanguage returns the base language of the first entry of an Accept-Language header,
+// e.g. "id" for "id-ID,id;q=0.9,en;q=0.8". It returns an empty string when no language is set.
+func PrimaryLanguage(acceptLanguage string) string {
+	first := strings.TrimSpace(strings.Split(acceptLanguage, ",")[0])
+	first = strings.TrimSpace(strings.Split(first, ";")[0])
+	if first == "" || first == "*" {
+		return ""
+	}
+	return strings.ToLower(strings.Split(first, "-")[0])
+}
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/** MailMessage is a plain text email */
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+/** Mailer delivers email, implementations must be safe for concurrent use */
+type Mailer interface {
+	Send(message MailMessage) error
+}
+
+/** MailerOptions selects and configures a mailer, see NewMailer */
+type MailerOptions struct {
+	Driver   string
+	From     string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	FilePath string
+	Timeout  time.Duration
+}
+
+/** NewMailer returns the mailer of options.Driver, "smtp" or "log" which writes mail to options.FilePath or the standard log */
+func NewMailer(options MailerOptions) (Mailer, error) {
+	switch options.Driver {
+	case "smtp":
+		if options.Host == "" || options.From == "" {
+			return nil, fmt.Errorf("smtp mailer needs a host and a from address")
+		}
+		if _, err := mail.ParseAddress(options.From); err != nil {
+			return nil, fmt.Errorf("invalid from address %q: %w", options.From, err)
+		}
+		return &SMTPMailer{
+			Host:     options.Host,
+			Port:     options.Port,
+			Username: options.Username,
+			Password: options.Password,
+			From:     options.From,
+			Timeout:  options.Timeout,
+		}, nil
+	case "", "log", "file":
+		return &LogMailer{From: options.From, Path: options.FilePath}, nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", options.Driver)
+}
+
+/** SMTPMailer sends mail through an SMTP server, using STARTTLS when the server offers it */
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	/** Timeout bounds the whole SMTP session, ten seconds when unset, net/smtp has no timeout of its own */
+	Timeout time.Duration
+}
+
+/** Send delivers message to the SMTP server, the envelope sender is the bare address of From without its display name */
+func (m *SMTPMailer) Send(message MailMessage) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return err
+	}
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(m.Host, strconv.Itoa(m.Port)), timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(formatMail(m.From, message, time.Now())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+/** LogMailer appends mail to Path, or writes it to the standard log, instead of sending it */
+type LogMailer struct {
+	From string
+	Path string
+	mu   sync.Mutex
+}
+
+/** Send writes message to the file or log */
+func (m *LogMailer) Send(message MailMessage) error {
+	data := formatMail(m.From, message, time.Now())
+	if m.Path == "" {
+		log.Printf("📧 Mail not sent, MAIL_DRIVER is log:\n%s", data)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, "\r\n"...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+/** formatMail renders message as RFC 5322 text, line breaks are removed from headers so a user value cannot add headers */
+func formatMail(from string, message MailMessage, date time.Time) []byte {
+	header := func(value string) string {
+		return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	}
+	var b strings.Builder
+	b.WriteString("From: " + header(from) + "\r\n")
+	b.WriteString("To: " + header(message.To) + "\r\n")
+	b.WriteString("Subject: " + header(message.Subject) + "\r\n")
+	b.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(message.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatMailStripsHeaderLineBreaks(t *testing.T) {
+	data := string(formatMail("Shortleak <no-reply@example.com>", MailMessage{
+		To:      "john@example.com",
+		Subject: "Hello\r\nBcc: attacker@example.com",
+		Body:    "line one\nline two",
+	}, time.Now()))
+
+	assert.Contains(t, data, "Subject: HelloBcc: attacker@example.com\r\n")
+	assert.NotContains(t, data, "\r\nBcc:")
+	assert.True(t, strings.HasSuffix(data, "\r\n\r\nline one\r\nline two"))
+}
+
+func TestLogMailerWritesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	mailer, err := NewMailer(MailerOptions{Driver: "file", From: "no-reply@example.com", FilePath: path})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mailer.Send(MailMessage{To: "john@example.com", Subject: "First", Body: "one"}))
+	assert.NoError(t, mailer.Send(MailMessage{To: "jane@example.com", Subject: "Second", Body: "two"}))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "To: john@example.com")
+	assert.Contains(t, string(data), "Subject: Second")
+}
+
+/** fakeSMTPServer menerima satu sesi SMTP dan mengirim perintah yang diterima ke channel */
+func fakeSMTPServer(t *testing.T) (string, int, <-chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		var lines []string
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 fake ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+				reply("250 fake")
+			case line == "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := reader.ReadString('\n')
+					if err != nil || data == ".\r\n" {
+						break
+					}
+					lines = append(lines, strings.TrimRight(data, "\r\n"))
+				}
+				reply("250 queued")
+			case line == "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+		received <- lines
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return host, portNumber, received
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	host, port, received := fakeSMTPServer(t)
+	mailer, err := NewMailer(MailerOptions{Driver: "smtp", Host: host, Port: port, From: "Shortleak <no-reply@example.com>"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mailer.Send(MailMessage{To: "john@example.com", Subject: "Hello", Body: "hi"}))
+
+	var lines []string
+	select {
+	case lines = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fake SMTP server did not finish the session")
+	}
+	// envelope sender hanya alamatnya, display name tetap di header From
+	assert.Contains(t, lines, "MAIL FROM:<no-reply@example.com>")
+	assert.Contains(t, lines, "RCPT TO:<john@example.com>")
+	assert.Contains(t, lines, "From: Shortleak <no-reply@example.com>")
+	assert.Contains(t, lines, "Subject: Hello")
+}
+
+func TestSMTPMailerSendTimesOut(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	// server menerima koneksi tapi tidak pernah menyapa
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(2 * time.Second)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	mailer := &SMTPMailer{Host: host, Port: portNumber, From: "no-reply@example.com", Timeout: 100 * time.Millisecond}
+
+	start := time.Now()
+	assert.Error(t, mailer.Send(MailMessage{To: "john@example.com", Subject: "Hello", Body: "hi"}))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestNewMailer(t *testing.T) {
+	mailer, err := NewMailer(MailerOptions{})
+	assert.NoError(t, err)
+	assert.IsType(t, &LogMailer{}, mailer)
+
+	mailer, err = NewMailer(MailerOptions{Driver: "smtp", Host: "smtp.example.com", Port: 587, From: "no-reply@example.com"})
+	assert.NoError(t, err)
+	assert.IsType(t, &SMTPMailer{}, mailer)
+
+	// smtp tanpa host ditolak
+	_, err = NewMailer(MailerOptions{Driver: "smtp"})
+	assert.Error(t, err)
+
+	// alamat from yang tidak bisa diparse ditolak saat start, bukan saat kirim
+	_, err = NewMailer(MailerOptions{Driver: "smtp", Host: "smtp.example.com", Port: 587, From: "Shortleak no-reply"})
+	assert.Error(t, err)
+
+	_, err = NewMailer(MailerOptions{Driver: "pigeon"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+/** AttemptLimiter counts failed attempts per key within a sliding window */
+type AttemptLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	attempts  map[string][]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+/** NewAttemptLimiter returns a limiter that blocks a key after limit failures within window */
+func NewAttemptLimiter(limit int, window time.Duration) *AttemptLimiter {
+	return &AttemptLimiter{
+		limit:    limit,
+		window:   window,
+		attempts: make(map[string][]time.Time),
+		now:      time.Now,
+	}
+}
+
+/** Blocked reports whether the key reached the failure limit within the window */
+func (l *AttemptLimiter) Blocked(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.prune(key, l.now())) >= l.limit
+}
+
+/** Fail records a failed attempt for the key and sweeps expired keys at most once per window */
+func (l *AttemptLimiter) Fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) >= l.window {
+		l.sweep(now)
+	}
+	l.attempts[key] = append(l.prune(key, now), now)
+}
+
+/** Reset clears the failed attempts of the key */
+func (l *AttemptLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.attempts, key)
+}
+
+/** sweep prunes every key so keys that are never seen again do not stay in memory, the caller must hold the lock */
+func (l *AttemptLimiter) sweep(now time.Time) {
+	for key := range l.attempts {
+		l.prune(key, now)
+	}
+	l.lastSweep = now
+}
+
+/** prune drops attempts outside the window, the caller must hold the lock */
+func (l *AttemptLimiter) prune(key string, now time.Time) []time.Time {
+	kept := l.attempts[key][:0]
+	for _, t := range l.attempts[key] {
+		if now.Sub(t) < l.window {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		delete(l.attempts, key)
+		return nil
+	}
+	l.attempts[key] = kept
+	return kept
+}
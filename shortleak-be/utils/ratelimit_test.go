@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/** newTestLimiter membuat limiter dengan jam yang bisa dimajukan dari test */
+func newTestLimiter(limit int, window time.Duration) (*AttemptLimiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewAttemptLimiter(limit, window)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestAttemptLimiterBlocksAfterLimit(t *testing.T) {
+	l, _ := newTestLimiter(2, time.Minute)
+
+	l.Fail("a")
+	assert.False(t, l.Blocked("a"))
+	l.Fail("a")
+	assert.True(t, l.Blocked("a"))
+
+	// key lain tidak ikut terblokir
+	assert.False(t, l.Blocked("b"))
+}
+
+func TestAttemptLimiterWindowExpires(t *testing.T) {
+	l, now := newTestLimiter(2, time.Minute)
+
+	l.Fail("a")
+	*now = now.Add(30 * time.Second)
+	l.Fail("a")
+	assert.True(t, l.Blocked("a"))
+
+	// percobaan pertama keluar dari window, yang kedua masih dihitung
+	*now = now.Add(31 * time.Second)
+	assert.False(t, l.Blocked("a"))
+	l.Fail("a")
+	assert.True(t, l.Blocked("a"))
+}
+
+func TestAttemptLimiterReset(t *testing.T) {
+	l, _ := newTestLimiter(2, time.Minute)
+
+	l.Fail("a")
+	l.Fail("a")
+	assert.True(t, l.Blocked("a"))
+
+	l.Reset("a")
+	assert.False(t, l.Blocked("a"))
+	assert.Equal(t, 0, len(l.attempts))
+}
+
+func TestAttemptLimiterSweepsExpiredKeys(t *testing.T) {
+	l, now := newTestLimiter(5, time.Minute)
+
+	l.Fail("a")
+	l.Fail("b")
+	assert.Equal(t, 2, len(l.attempts))
+
+	// key lama dihapus walaupun tidak pernah dicek lagi
+	*now = now.Add(2 * time.Minute)
+	l.Fail("c")
+	assert.Equal(t, 1, len(l.attempts))
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"strings"
+)
+
+/** UserAgentInfo is the browser, operating system and device class parsed from a User-Agent header */
+type UserAgentInfo struct {
+	Browser string `json:"browser"`
+	OS      string `json:"os"`
+	Device  string `json:"device"`
+}
+
+/** uaRule maps a User-Agent substring to a name, first match wins */
+type uaRule struct {
+	token string
+	name  string
+}
+
+var botTokens = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "curl/", "wget/", "python-requests", "go-http-client"}
+
+var browserRules = []uaRule{
+	{"edg/", "Edge"},
+	{"edge/", "Edge"},
+	{"opr/", "Opera"},
+	{"opera", "Opera"},
+	{"samsungbrowser/", "Samsung Internet"},
+	{"firefox/", "Firefox"},
+	{"fxios/", "Firefox"},
+	{"crios/", "Chrome"},
+	{"chrome/", "Chrome"},
+	{"msie ", "Internet Explorer"},
+	{"trident/", "Internet Explorer"},
+	{"safari/", "Safari"},
+}
+
+var osRules = []uaRule{
+	{"iphone", "iOS"},
+	{"ipad", "iOS"},
+	{"ipod", "iOS"},
+	{"android", "Android"},
+	{"windows", "Windows"},
+	{"cros ", "ChromeOS"},
+	{"mac os x", "macOS"},
+	{"macintosh", "macOS"},
+	{"linux", "Linux"},
+}
+
+/** ParseUserAgent extracts the browser, operating system and device class (desktop, mobile, tablet, bot) from a User-Agent header, unknown values are reported as "Other" or "unknown" */
+func ParseUserAgent(userAgent string) UserAgentInfo {
+	ua := strings.ToLower(userAgent)
+	info := UserAgentInfo{Browser: "Other", OS: "Other", Device: "unknown"}
+	if ua == "" {
+		return info
+	}
+
+	for _, rule := range browserRules {
+		if strings.Contains(ua, rule.token) {
+			info.Browser = rule.name
+			break
+		}
+	}
+	for _, rule := range osRules {
+		if strings.Contains(ua, rule.token) {
+			info.OS = rule.name
+			break
+		}
+	}
+
+	switch {
+	case containsAny(ua, botTokens):
+		info.Device = "bot"
+		info.Browser = "Bot"
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet") ||
+		(strings.Contains(ua, "android") && !strings.Contains(ua, "mobile")):
+		info.Device = "tablet"
+	case strings.Contains(ua, "mobi") || strings.Contains(ua, "iphone") || strings.Contains(ua, "ipod"):
+		info.Device = "mobile"
+	case info.OS == "Windows" || info.OS == "macOS" || info.OS == "Linux" || info.OS == "ChromeOS":
+		info.Device = "desktop"
+	}
+	return info
+}
+
+/** CanonicalOS returns the spelling ParseUserAgent reports for an operating system, false for one it never reports */
+func CanonicalOS(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if strings.EqualFold(name, "Other") {
+		return "Other", true
+	}
+	for _, rule := range osRules {
+		if strings.EqualFold(name, rule.name) {
+			return rule.name, true
+		}
+	}
+	return "", false
+}
+
+func containsAny(s string, tokens []string) bool {
+	for _, token := range tokens {
+		if strings.Contains(s, token) {
+			return true
+		}
+	}
+	return false
+}
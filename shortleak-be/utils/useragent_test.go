@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	cases := []struct {
+		ua       string
+		expected UserAgentInfo
+	}{
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			UserAgentInfo{Browser: "Safari", OS: "iOS", Device: "mobile"},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			UserAgentInfo{Browser: "Chrome", OS: "Android", Device: "mobile"},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			UserAgentInfo{Browser: "Chrome", OS: "Android", Device: "tablet"},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
+			UserAgentInfo{Browser: "Edge", OS: "Windows", Device: "desktop"},
+		},
+		{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.0; rv:121.0) Gecko/20100101 Firefox/121.0",
+			UserAgentInfo{Browser: "Firefox", OS: "macOS", Device: "desktop"},
+		},
+		{
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			UserAgentInfo{Browser: "Bot", OS: "Other", Device: "bot"},
+		},
+		{
+			"",
+			UserAgentInfo{Browser: "Other", OS: "Other", Device: "unknown"},
+		},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, ParseUserAgent(tc.ua), tc.ua)
+	}
+}
+
+func TestCanonicalOS(t *testing.T) {
+	os, ok := CanonicalOS("macos")
+	assert.True(t, ok)
+	assert.Equal(t, "macOS", os)
+
+	os, ok = CanonicalOS(" IOS ")
+	assert.True(t, ok)
+	assert.Equal(t, "iOS", os)
+
+	// nama yang tidak pernah dihasilkan parser ditolak
+	_, ok = CanonicalOS("Mac OS X")
+	assert.False(t, ok)
+}
+
+func TestReferrerDomain(t *testing.T) {
+	assert.Equal(t, "news.ycombinator.com", ReferrerDomain("https://news.ycombinator.com/item?id=1"))
+	assert.Equal(t, "google.com", ReferrerDomain("https://www.Google.com/"))
+	assert.Equal(t, "", ReferrerDomain(""))
+	assert.Equal(t, "", ReferrerDomain("not a url"))
+}
+
+func TestPrimaryLanguage(t *testing.T) {
+	assert.Equal(t, "id", PrimaryLanguage("id-ID,id;q=0.9,en-US;q=0.8"))
+	assert.Equal(t, "en", PrimaryLanguage("EN"))
+	assert.Equal(t, "", PrimaryLanguage(""))
+	assert.Equal(t, "", PrimaryLanguage("*"))
+}
@@ -1,8 +1,10 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -42,6 +44,13 @@ func init() {
 	validate.RegisterValidation("password", validatePassword)
 	/** Register custom validation for URL */
 	validate.RegisterValidation("url", validateUrl)
+	/** Register custom validation for short link alias */
+	validate.RegisterValidation("alias", validateAlias)
+}
+
+/** ReservedAliases are words that cannot be used as a custom alias because they clash with routes */
+var ReservedAliases = []string{
+	"404", "admin", "api", "assets", "auth", "links", "login", "logout", "register", "shorten", "static", "stats",
 }
 
 /** ValidateStruct validates the struct and returns a map of validation errors */
@@ -72,6 +81,8 @@ func msgForTag(tag string, param string) string {
 		return "password must be at least 8 characters, contain 1 uppercase, 1 lowercase, 1 number, and 1 special character"
 	case "url":
 		return "invalid URL format"
+	case "alias":
+		return "alias may only contain letters, numbers, '-' and '_', and must not be a reserved word"
 	}
 	return "invalid field"
 }
@@ -118,3 +129,30 @@ func ValidateUrlDirect(url string) error {
 	}
 	return nil
 }
+
+/** validateAlias checks the alias charset and rejects reserved words */
+func validateAlias(fl validator.FieldLevel) bool {
+	alias := fl.Field().String()
+	var aliasRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	if !aliasRegex.MatchString(alias) {
+		return false
+	}
+	for _, reserved := range ReservedAliases {
+		if strings.EqualFold(alias, reserved) {
+			return false
+		}
+	}
+	return true
+}
+
+/** ValidateAliasDirect validates a custom alias and returns a user-friendly error */
+func ValidateAliasDirect(alias string) error {
+	if err := validate.Var(alias, "min=3,max=32,alias"); err != nil {
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) && len(errs) > 0 {
+			return errors.New(msgForTag(errs[0].Tag(), errs[0].Param()))
+		}
+		return err
+	}
+	return nil
+}